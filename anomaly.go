@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"net"
+
+	"./dhcp"
+)
+
+// Routes with prefixes shorter than this, other than the default route,
+// are reported as suspicious.
+const broadPrefix = 8
+
+// checkRoutes looks for routes in server replies that steal traffic from
+// other interfaces, such as VPN tunnels, by replacing the default route
+// or covering large parts of the address space (CVE-2024-3661, also known
+// as "TunnelVision").
+func checkRoutes(p *dhcp.Packet) []string {
+	if p.Op != dhcp.BootReply {
+		return nil
+	}
+
+	routes, err := p.Routes()
+	if err != nil {
+		stats.warn["invalid routes"]++
+		return []string{"invalid static route data"}
+	}
+
+	var router net.IP
+	if b, ok := p.Option(dhcp.Router); ok && len(b) >= 4 {
+		router = net.IP(b[:4])
+	}
+
+	var warnings []string
+	for _, r := range routes {
+		bits, _ := r.Dest.Mask.Size()
+		switch {
+		case bits == 0 && router == nil:
+			warnings = append(warnings, fmt.Sprintf(
+				"default route via %s without router option", r.Gateway))
+		case bits == 0:
+			if r.Gateway.Equal(router) {
+				continue
+			}
+			warnings = append(warnings, fmt.Sprintf(
+				"route %s overrides default router %s", r, router))
+		case bits < broadPrefix:
+			warnings = append(warnings, fmt.Sprintf(
+				"route %s covers a broad prefix (TunnelVision)", r))
+		default:
+			continue
+		}
+		stats.warn["suspicious routes"]++
+	}
+
+	return warnings
+}
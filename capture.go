@@ -0,0 +1,92 @@
+package main
+
+import (
+	"./dhcp"
+	"fmt"
+	"net"
+	"time"
+)
+
+var (
+	capture   *dhcp.CaptureWriter // file packets are written to, if any
+	allowList []*allowed          // servers expected to answer, if any
+)
+
+func openCapture(name string) {
+	if name == "" {
+		return
+	}
+	var err error
+	capture, err = dhcp.CreateCapture(name)
+	checkError(err)
+}
+
+func closeCapture() {
+	if capture == nil {
+		return
+	}
+	err := capture.Close()
+	capture = nil
+	checkError(err)
+}
+
+// verdict returns notes about a packet received from the given address,
+// starting with the warnings found when showing it. Replies from servers
+// not in the allow list are reported.
+func verdict(p *dhcp.Packet, ip, mac string, warnings []string) []string {
+	notes := append([]string{}, warnings...)
+	if p.Op == dhcp.BootReply && len(allowList) > 0 {
+		if isAllowed(allowList, offer{ip, mac}) {
+			notes = append(notes, "allowed server")
+		} else {
+			fmt.Println("Warning: server not in allow list")
+			stats.warn["rogue server"]++
+			notes = append(notes, "rogue server")
+		}
+	}
+	return notes
+}
+
+// record writes a packet sent or received at time t to the capture file,
+// with the given notes as comments. Headers are synthesized from the
+// addresses known; unknown destinations are broadcast.
+func record(t time.Time, p *dhcp.Packet, srcIP, srcMAC string, dstIP, dstMAC string, notes []string) {
+	if capture == nil {
+		return
+	}
+
+	data, err := p.Encode(0)
+	if err != nil {
+		return
+	}
+
+	f := &dhcp.Frame{
+		SrcMAC:  parseMAC(srcMAC),
+		DstMAC:  parseMAC(dstMAC),
+		SrcIP:   parseIP(srcIP),
+		DstIP:   parseIP(dstIP),
+		SrcPort: dhcp.ClientPort,
+		DstPort: dhcp.ServerPort,
+		Payload: data,
+	}
+	if p.Op == dhcp.BootReply {
+		f.SrcPort, f.DstPort = dhcp.ServerPort, dhcp.ClientPort
+	}
+
+	err = capture.WriteFrame(t, f, notes...)
+	checkError(err)
+}
+
+func parseMAC(s string) net.HardwareAddr {
+	if hw, err := net.ParseMAC(s); err == nil {
+		return hw
+	}
+	return net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+}
+
+func parseIP(s string) net.IP {
+	if ip := net.ParseIP(s); ip != nil {
+		return ip
+	}
+	return net.IPv4bcast
+}
@@ -0,0 +1,164 @@
+package dhcp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+// DefaultDiscoverTimeout is how long Discover waits for offers if no
+// timeout is set.
+const DefaultDiscoverTimeout = 5 * time.Second
+
+// DiscoverOptions sets how a discover packet is built and sent. The zero
+// value sends from the interface hardware address using UDP sockets.
+type DiscoverOptions struct {
+	Timeout     time.Duration    // time to wait for offers
+	ClientMAC   net.HardwareAddr // client hardware address to use
+	VendorClass string           // Vendor Class Identifier option
+	Parameters  []byte           // Parameter Request List option
+	RelayAddr   net.IP           // relay agent address, to act as a relay
+	RelayInfo   *RelayAgentInfo  // Relay Agent Information option
+	Raw         bool             // use a raw socket bound to the interface
+	SendOnly    bool             // don't wait for offers
+	Peer        Peer             // send and receive through this peer
+
+	// Sent, if set, is called with the discover packet once sent.
+	Sent func(p *Packet)
+
+	// Received, if set, is called with each offer as it arrives.
+	Received func(o *Offer)
+}
+
+// Offer is a DHCPOFFER received in reply to a discover.
+type Offer struct {
+	ServerIP  net.IP
+	ServerMAC net.HardwareAddr // nil if not known
+	Latency   time.Duration
+	Options   map[byte]interface{} // decoded option values
+	Packet    *Packet
+}
+
+// Discover broadcasts a DHCPDISCOVER on the interface and returns the
+// offers received until the timeout expires or the context is done.
+func Discover(ctx context.Context, iface string, opts *DiscoverOptions) ([]Offer, error) {
+	if opts == nil {
+		opts = &DiscoverOptions{}
+	}
+
+	mac := opts.ClientMAC
+	if mac == nil {
+		ifi, err := net.InterfaceByName(iface)
+		if err != nil {
+			return nil, err
+		}
+		mac = ifi.HardwareAddr
+	}
+	if len(mac) != 6 {
+		return nil, errors.New("dhcp: invalid client hardware address")
+	}
+
+	peer := opts.Peer
+	if peer == nil {
+		var err error
+		switch {
+		case opts.Raw:
+			peer, err = NewRawClient(iface)
+		case opts.SendOnly:
+			peer, err = NewClientNotListening()
+		default:
+			peer, err = NewClient()
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer peer.Close()
+	}
+
+	p := NewDiscoverPacket()
+	copy(p.Chaddr[:], mac)
+	if opts.VendorClass != "" {
+		if err := p.SetString(VendorClassIdentifier, opts.VendorClass); err != nil {
+			return nil, err
+		}
+	}
+	if len(opts.Parameters) > 0 {
+		if err := p.SetOption(ParameterRequestList, opts.Parameters); err != nil {
+			return nil, err
+		}
+	}
+	if ip := opts.RelayAddr.To4(); ip != nil {
+		copy(p.Giaddr[:], ip)
+		p.Hops = 1
+	}
+	if opts.RelayInfo != nil {
+		if err := p.SetRelayAgentInfo(opts.RelayInfo); err != nil {
+			return nil, err
+		}
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultDiscoverTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := peer.Broadcast(ctx, p); err != nil {
+		return nil, err
+	}
+	if opts.Sent != nil {
+		opts.Sent(p)
+	}
+	if opts.SendOnly {
+		return nil, nil
+	}
+
+	var offers []Offer
+	for {
+		o, remote, err := peer.Receive(ctx)
+		if err == context.DeadlineExceeded {
+			return offers, nil
+		}
+		if err != nil {
+			if remote != nil {
+				// malformed packet
+				continue
+			}
+			return offers, err
+		}
+
+		if o.Xid != p.Xid || o.Chaddr != p.Chaddr || o.MessageType() != DHCPOffer {
+			continue
+		}
+
+		offer := Offer{
+			ServerIP: remote.IP,
+			Latency:  time.Since(start),
+			Options:  map[byte]interface{}{},
+			Packet:   &o,
+		}
+		if lp, ok := peer.(LinkPeer); ok {
+			offer.ServerMAC = lp.RemoteMAC()
+		}
+		if list, err := o.DecodeOptions(); err == nil {
+			for _, x := range list {
+				if x.Type == PadOption || x.Type == EndOption {
+					continue
+				}
+				v, err := x.Value()
+				if err != nil {
+					v = x.Data
+				}
+				offer.Options[x.Type] = v
+			}
+		}
+
+		if opts.Received != nil {
+			opts.Received(&offer)
+		}
+		offers = append(offers, offer)
+	}
+}
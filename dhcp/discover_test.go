@@ -0,0 +1,104 @@
+package dhcp
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestDiscover(t *testing.T) {
+	_, cl := newTestSegment(t)
+
+	offers, err := Discover(context.Background(), "", &DiscoverOptions{
+		Timeout:     100 * time.Millisecond,
+		ClientMAC:   net.HardwareAddr{1, 2, 3, 4, 5, 6},
+		VendorClass: "test",
+		Parameters:  []byte{Router, DomainNameServer},
+		Peer:        cl,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(offers) != 2 {
+		t.Fatalf("expect 2 offers, got %d", len(offers))
+	}
+
+	for _, o := range offers {
+		if o.ServerMAC == nil || o.Latency <= 0 {
+			t.Fatalf("unexpected offer %+v", o)
+		}
+		if d := o.Options[IPAddressLeaseTime]; d != time.Hour {
+			t.Fatalf("expect lease time %s, got %v", time.Hour, d)
+		}
+		if ip, ok := o.Options[ServerIdentifier].(net.IP); !ok || !ip.Equal(o.ServerIP) {
+			t.Fatalf("expect server identifier %s, got %v", o.ServerIP, o.Options[ServerIdentifier])
+		}
+	}
+}
+
+func TestDiscoverCancel(t *testing.T) {
+	seg := NewSegment()
+	cl := seg.NewClient(net.HardwareAddr{1, 2, 3, 4, 5, 6})
+	defer cl.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := Discover(ctx, "", &DiscoverOptions{
+		ClientMAC: net.HardwareAddr{1, 2, 3, 4, 5, 6},
+		Peer:      cl,
+	})
+	if err != context.Canceled {
+		t.Fatalf("expect %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestDiscoverRelay(t *testing.T) {
+	_, cl := newTestSegment(t)
+
+	var sent *Packet
+	var received int
+	offers, err := Discover(context.Background(), "", &DiscoverOptions{
+		Timeout:   100 * time.Millisecond,
+		ClientMAC: net.HardwareAddr{1, 2, 3, 4, 5, 6},
+		RelayAddr: net.IPv4(10, 0, 0, 254),
+		RelayInfo: &RelayAgentInfo{CircuitID: []byte("eth0")},
+		Peer:      cl,
+		Sent:      func(p *Packet) { sent = p },
+		Received:  func(o *Offer) { received++ },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sent == nil {
+		t.Fatal("sent packet not reported")
+	}
+	if !sent.Giaddr.IP().Equal(net.IPv4(10, 0, 0, 254)) || sent.Hops != 1 {
+		t.Fatalf("unexpected relay address %s, hops %d", sent.Giaddr.IP(), sent.Hops)
+	}
+	r, err := sent.RelayAgentInfo()
+	if err != nil || r == nil || string(r.CircuitID) != "eth0" {
+		t.Fatalf("unexpected relay agent information %+v, %v", r, err)
+	}
+	if received != len(offers) {
+		t.Fatalf("expect %d offers reported, got %d", len(offers), received)
+	}
+}
+
+func TestDiscoverSendOnly(t *testing.T) {
+	_, cl := newTestSegment(t)
+
+	offers, err := Discover(context.Background(), "", &DiscoverOptions{
+		ClientMAC: net.HardwareAddr{1, 2, 3, 4, 5, 6},
+		SendOnly:  true,
+		Peer:      cl,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(offers) != 0 {
+		t.Fatalf("expect no offers, got %d", len(offers))
+	}
+}
@@ -0,0 +1,82 @@
+package dhcp
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalidDomain = errors.New("dhcp: invalid domain name encoding")
+
+// decodeDomainList decodes a list of domain names in RFC 1035 wire
+// format, with message compression relative to the start of b as used in
+// the Domain Search option (RFC 3397).
+func decodeDomainList(b []byte) ([]string, error) {
+	var list []string
+	for i := 0; i < len(b); {
+		name, n, err := decodeDomain(b, i)
+		if err != nil {
+			return list, err
+		}
+		list = append(list, name)
+		i = n
+	}
+	return list, nil
+}
+
+// decodeDomain decodes the domain name starting at offset i, and returns
+// the offset of the data following it.
+func decodeDomain(b []byte, i int) (string, int, error) {
+	var labels []string
+	next := -1
+	for jumps := 0; ; {
+		if i >= len(b) {
+			return "", 0, ErrInvalidDomain
+		}
+		l := int(b[i])
+		switch {
+		case l == 0:
+			if next < 0 {
+				next = i + 1
+			}
+			return strings.Join(labels, "."), next, nil
+		case l&0xc0 == 0xc0:
+			// compression pointer
+			if i+1 >= len(b) || jumps > len(b) {
+				return "", 0, ErrInvalidDomain
+			}
+			if next < 0 {
+				next = i + 2
+			}
+			i = (l&0x3f)<<8 | int(b[i+1])
+			jumps++
+		case l&0xc0 != 0:
+			return "", 0, ErrInvalidDomain
+		default:
+			if i+1+l > len(b) {
+				return "", 0, ErrInvalidDomain
+			}
+			labels = append(labels, string(b[i+1:i+1+l]))
+			i += 1 + l
+		}
+	}
+}
+
+// encodeDomainList encodes a list of domain names in uncompressed RFC 1035
+// wire format.
+func encodeDomainList(list []string) ([]byte, error) {
+	var b []byte
+	for _, name := range list {
+		name = strings.TrimSuffix(name, ".")
+		if name != "" {
+			for _, label := range strings.Split(name, ".") {
+				if len(label) == 0 || len(label) > 63 {
+					return nil, ErrInvalidDomain
+				}
+				b = append(b, byte(len(label)))
+				b = append(b, label...)
+			}
+		}
+		b = append(b, 0)
+	}
+	return b, nil
+}
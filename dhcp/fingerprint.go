@@ -0,0 +1,173 @@
+package dhcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Fingerprint describes the requests sent by a class of client devices.
+// Empty fields match any request.
+type Fingerprint struct {
+	Class       string // device class, such as "Windows 10/11"
+	PRL         string // parameter request list, in order
+	VendorClass string // vendor class identifier prefix
+	Options     string // options the request must carry
+}
+
+// FingerprintDB is a list of fingerprints. When several fingerprints
+// match, the most specific one wins; ties go to the first in the list.
+type FingerprintDB []Fingerprint
+
+// DefaultFingerprints are the built-in client fingerprints. Option lists
+// are comma-separated option codes.
+var DefaultFingerprints = FingerprintDB{
+	{Class: "Windows 10/11", PRL: "1,3,6,15,31,33,43,44,46,47,119,121,249,252", VendorClass: "MSFT 5.0"},
+	{Class: "Windows 7/8", PRL: "1,15,3,6,44,46,47,31,33,121,249,43", VendorClass: "MSFT 5.0"},
+	{Class: "Windows", VendorClass: "MSFT"},
+	{Class: "macOS", PRL: "1,121,3,6,15,108,114,119,252,95,44,46"},
+	{Class: "macOS", PRL: "1,121,3,6,15,119,252,95,44,46"},
+	{Class: "iOS", PRL: "1,121,3,6,15,108,114,119,252"},
+	{Class: "iOS", PRL: "1,121,3,6,15,119,252"},
+	{Class: "Android", PRL: "1,3,6,15,26,28,51,58,59,43,114,108", VendorClass: "android-dhcp-"},
+	{Class: "Android", PRL: "1,3,6,15,26,28,51,58,59,43", VendorClass: "android-dhcp-"},
+	{Class: "Android", VendorClass: "android-dhcp-"},
+	{Class: "Linux (dhclient)", PRL: "1,28,2,3,15,6,119,12,44,47,26,121,42"},
+	{Class: "Linux (systemd-networkd)", PRL: "1,3,6,12,15,28,42,119,121"},
+	{Class: "Linux (dhcpcd)", VendorClass: "dhcpcd-"},
+	{Class: "Embedded Linux (udhcpc)", VendorClass: "udhcp "},
+	{Class: "Printer (HP)", VendorClass: "Hewlett-Packard JetDirect"},
+	{Class: "Printer (HP)", PRL: "1,3,44,6,7,12,15,22,54,58,59,69,18,144"},
+	{Class: "Printer (Canon)", VendorClass: "Canon"},
+	{Class: "Printer (Brother)", VendorClass: "Brother"},
+	{Class: "IP phone (Cisco)", VendorClass: "Cisco Systems, Inc. IP Phone"},
+	{Class: "IP phone (Cisco)", PRL: "1,66,6,3,15,150,35"},
+	{Class: "IP phone (Polycom)", VendorClass: "Polycom-"},
+	{Class: "IP phone (Yealink)", VendorClass: "yealink"},
+	{Class: "IP phone (Avaya)", VendorClass: "ccp.avaya.com"},
+}
+
+// LoadFingerprints reads a JSON list of fingerprints.
+func LoadFingerprints(r io.Reader) (FingerprintDB, error) {
+	var db FingerprintDB
+	if err := json.NewDecoder(r).Decode(&db); err != nil {
+		return nil, err
+	}
+	for i := range db {
+		f := &db[i]
+		if f.Class == "" {
+			return nil, errors.New("dhcp: fingerprint without class")
+		}
+		// normalize option lists for comparison
+		for _, s := range []*string{&f.PRL, &f.Options} {
+			b, err := parseOptionList(*s)
+			if err != nil {
+				return nil, fmt.Errorf("dhcp: fingerprint %q: %s", f.Class, err)
+			}
+			*s = optionListString(b)
+		}
+	}
+	return db, nil
+}
+
+// LoadFingerprintFile reads a JSON list of fingerprints from a file.
+func LoadFingerprintFile(name string) (FingerprintDB, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadFingerprints(f)
+}
+
+// RequestFingerprint returns the fingerprint of a client request, with
+// its parameter request list, vendor class and options in order.
+func RequestFingerprint(p *Packet) Fingerprint {
+	var f Fingerprint
+	opts, _ := p.DecodeOptions()
+	var codes []byte
+	for _, o := range opts {
+		switch o.Type {
+		case EndOption:
+			continue
+		case ParameterRequestList:
+			f.PRL = optionListString(o.Data)
+		case VendorClassIdentifier:
+			f.VendorClass = string(o.Data)
+		}
+		codes = append(codes, o.Type)
+	}
+	f.Options = optionListString(codes)
+	return f
+}
+
+// Match returns the most specific fingerprint matching a client request.
+func (db FingerprintDB) Match(p *Packet) (Fingerprint, bool) {
+	req := RequestFingerprint(p)
+	have, _ := parseOptionList(req.Options)
+
+	var best Fingerprint
+	score := 0
+	for _, f := range db {
+		n := f.match(req, have)
+		if n > score {
+			best, score = f, n
+		}
+	}
+	return best, score > 0
+}
+
+// match returns how specific a fingerprint matching the request is, or
+// zero if it doesn't match.
+func (f *Fingerprint) match(req Fingerprint, have []byte) int {
+	score := 0
+	if f.PRL != "" {
+		if f.PRL != req.PRL {
+			return 0
+		}
+		score += 4
+	}
+	if f.VendorClass != "" {
+		if !strings.HasPrefix(req.VendorClass, f.VendorClass) {
+			return 0
+		}
+		score += 2
+	}
+	if f.Options != "" {
+		want, _ := parseOptionList(f.Options)
+		for _, c := range want {
+			if strings.IndexByte(string(have), c) < 0 {
+				return 0
+			}
+		}
+		score++
+	}
+	return score
+}
+
+func optionListString(b []byte) string {
+	var list []string
+	for _, c := range b {
+		list = append(list, strconv.Itoa(int(c)))
+	}
+	return strings.Join(list, ",")
+}
+
+func parseOptionList(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var b []byte
+	for _, x := range strings.Split(s, ",") {
+		c, err := strconv.ParseUint(strings.TrimSpace(x), 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid option code %q", x)
+		}
+		b = append(b, byte(c))
+	}
+	return b, nil
+}
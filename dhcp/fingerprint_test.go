@@ -0,0 +1,86 @@
+package dhcp
+
+import (
+	"strings"
+	"testing"
+)
+
+func newFingerprintRequest(prl []byte, class string) *Packet {
+	p := NewDiscoverPacket()
+	if prl != nil {
+		p.SetOption(ParameterRequestList, prl)
+	}
+	if class != "" {
+		p.SetString(VendorClassIdentifier, class)
+	}
+	return p
+}
+
+func TestFingerprintMatch(t *testing.T) {
+	tests := []struct {
+		prl   []byte
+		class string
+		want  string
+	}{
+		{[]byte{1, 3, 6, 15, 31, 33, 43, 44, 46, 47, 119, 121, 249, 252}, "MSFT 5.0", "Windows 10/11"},
+		{[]byte{1, 3, 6}, "MSFT 5.0", "Windows"},
+		{[]byte{1, 121, 3, 6, 15, 119, 252}, "", "iOS"},
+		{[]byte{1, 3, 6, 15, 26, 28, 51, 58, 59, 43}, "android-dhcp-13", "Android"},
+		{[]byte{1, 3, 6}, "udhcp 1.36.1", "Embedded Linux (udhcpc)"},
+		{[]byte{1, 3, 6}, "", ""},
+	}
+
+	for _, tt := range tests {
+		f, ok := DefaultFingerprints.Match(newFingerprintRequest(tt.prl, tt.class))
+		if ok != (tt.want != "") || f.Class != tt.want {
+			t.Errorf("%v %q: expect %q, got %q", tt.prl, tt.class, tt.want, f.Class)
+		}
+	}
+}
+
+func TestFingerprintSpecific(t *testing.T) {
+	db := FingerprintDB{
+		{Class: "any vendor", VendorClass: "acme"},
+		{Class: "with options", VendorClass: "acme", Options: "53,60,12"},
+		{Class: "missing options", VendorClass: "acme", Options: "53,60,81"},
+	}
+
+	p := newFingerprintRequest(nil, "acme-1.0")
+	p.SetString(HostName, "device")
+
+	f, ok := db.Match(p)
+	if !ok || f.Class != "with options" {
+		t.Fatalf("expect with options, got %q", f.Class)
+	}
+}
+
+func TestRequestFingerprint(t *testing.T) {
+	f := RequestFingerprint(newFingerprintRequest([]byte{1, 3, 6}, "acme"))
+	if f.PRL != "1,3,6" || f.VendorClass != "acme" || f.Options != "53,55,60" {
+		t.Fatalf("unexpected fingerprint %+v", f)
+	}
+}
+
+func TestLoadFingerprints(t *testing.T) {
+	db, err := LoadFingerprints(strings.NewReader(`[
+		{"Class": "Test device", "PRL": "1, 3, 6", "VendorClass": "test"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, ok := db.Match(newFingerprintRequest([]byte{1, 3, 6}, "test"))
+	if !ok || f.Class != "Test device" {
+		t.Fatalf("expect Test device, got %q", f.Class)
+	}
+
+	for _, s := range []string{
+		`[{"PRL": "1,3,6"}]`,
+		`[{"Class": "Bad", "PRL": "1,300"}]`,
+		`{"Class": "Not a list"}`,
+	} {
+		if _, err := LoadFingerprints(strings.NewReader(s)); err == nil {
+			t.Errorf("%s: expect error", s)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package dhcp
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+)
+
+const (
+	etherHeaderSize = 14
+	ipHeaderSize    = 20
+	udpHeaderSize   = 8
+
+	etherTypeIPv4 = 0x0800
+	etherTypeVLAN = 0x8100
+	protocolUDP   = 17
+
+	ClientPort = 68
+	ServerPort = 67
+)
+
+var ErrNotUDP = errors.New("dhcp: not an IPv4 UDP datagram")
+
+// Frame is a UDP datagram with its Ethernet and IPv4 headers.
+type Frame struct {
+	SrcMAC  net.HardwareAddr
+	DstMAC  net.HardwareAddr
+	SrcIP   net.IP
+	DstIP   net.IP
+	SrcPort int
+	DstPort int
+	Payload []byte
+}
+
+// IsDHCP reports whether the frame is addressed to or from a DHCP port.
+func (f *Frame) IsDHCP() bool {
+	switch f.DstPort {
+	case ClientPort, ServerPort:
+		return f.SrcPort == ClientPort || f.SrcPort == ServerPort
+	}
+	return false
+}
+
+// Source returns the address of the sender of the frame.
+func (f *Frame) Source() *net.UDPAddr {
+	return &net.UDPAddr{IP: f.SrcIP, Port: f.SrcPort}
+}
+
+// EncodeFrame builds an Ethernet frame containing the IPv4 UDP datagram.
+func EncodeFrame(f *Frame) []byte {
+	size := etherHeaderSize + ipHeaderSize + udpHeaderSize + len(f.Payload)
+	b := make([]byte, size)
+
+	// Ethernet
+	copy(b[0:6], f.DstMAC)
+	copy(b[6:12], f.SrcMAC)
+	binary.BigEndian.PutUint16(b[12:], etherTypeIPv4)
+
+	// IPv4
+	ip := b[etherHeaderSize:]
+	ip[0] = 0x45 // version 4, 5 words
+	binary.BigEndian.PutUint16(ip[2:], uint16(size-etherHeaderSize))
+	ip[8] = 64 // TTL
+	ip[9] = protocolUDP
+	copy(ip[12:16], f.SrcIP.To4())
+	copy(ip[16:20], f.DstIP.To4())
+	binary.BigEndian.PutUint16(ip[10:], checksum(ip[:ipHeaderSize], 0))
+
+	// UDP
+	udp := ip[ipHeaderSize:]
+	binary.BigEndian.PutUint16(udp[0:], uint16(f.SrcPort))
+	binary.BigEndian.PutUint16(udp[2:], uint16(f.DstPort))
+	binary.BigEndian.PutUint16(udp[4:], uint16(len(udp)))
+	copy(udp[udpHeaderSize:], f.Payload)
+
+	// pseudo header
+	sum := sum16(ip[12:20], 0) + protocolUDP + uint32(len(udp))
+	cs := checksum(udp, sum)
+	if cs == 0 {
+		cs = 0xffff
+	}
+	binary.BigEndian.PutUint16(udp[6:], cs)
+
+	return b
+}
+
+// DecodeFrame parses an Ethernet frame containing an IPv4 UDP datagram.
+// 802.1Q VLAN tags are skipped.
+func DecodeFrame(b []byte) (*Frame, error) {
+	if len(b) < etherHeaderSize {
+		return nil, ErrNotUDP
+	}
+
+	f := &Frame{
+		DstMAC: net.HardwareAddr(append([]byte{}, b[0:6]...)),
+		SrcMAC: net.HardwareAddr(append([]byte{}, b[6:12]...)),
+	}
+
+	i := 12
+	for len(b) >= i+2 && binary.BigEndian.Uint16(b[i:]) == etherTypeVLAN {
+		i += 4
+	}
+	if len(b) < i+2 || binary.BigEndian.Uint16(b[i:]) != etherTypeIPv4 {
+		return nil, ErrNotUDP
+	}
+
+	return f, decodeIPv4(b[i+2:], f)
+}
+
+// decodeIPv4 parses an IPv4 UDP datagram into the frame.
+func decodeIPv4(b []byte, f *Frame) error {
+	if len(b) < ipHeaderSize || b[0]>>4 != 4 || b[9] != protocolUDP {
+		return ErrNotUDP
+	}
+
+	hl := int(b[0]&0x0f) * 4
+	tl := int(binary.BigEndian.Uint16(b[2:]))
+	if hl < ipHeaderSize || tl < hl+udpHeaderSize || tl > len(b) {
+		return ErrNotUDP
+	}
+
+	// fragments are not reassembled
+	if binary.BigEndian.Uint16(b[6:])&0x3fff != 0 {
+		return ErrNotUDP
+	}
+
+	f.SrcIP = net.IPv4(b[12], b[13], b[14], b[15])
+	f.DstIP = net.IPv4(b[16], b[17], b[18], b[19])
+
+	udp := b[hl:tl]
+	ul := int(binary.BigEndian.Uint16(udp[4:]))
+	if ul < udpHeaderSize || ul > len(udp) {
+		return ErrNotUDP
+	}
+
+	f.SrcPort = int(binary.BigEndian.Uint16(udp[0:]))
+	f.DstPort = int(binary.BigEndian.Uint16(udp[2:]))
+	f.Payload = append([]byte{}, udp[udpHeaderSize:ul]...)
+
+	return nil
+}
+
+func sum16(b []byte, sum uint32) uint32 {
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	return sum
+}
+
+// checksum returns the Internet checksum of b (RFC 1071), starting from
+// a partial sum.
+func checksum(b []byte, sum uint32) uint16 {
+	sum = sum16(b, sum)
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,82 @@
+package dhcp
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	f := &Frame{
+		SrcMAC:  net.HardwareAddr{1, 2, 3, 4, 5, 6},
+		DstMAC:  net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		SrcIP:   net.IPv4zero,
+		DstIP:   net.IPv4bcast,
+		SrcPort: ClientPort,
+		DstPort: ServerPort,
+		Payload: []byte("hello, world"),
+	}
+
+	b := EncodeFrame(f)
+
+	// header checksums must verify
+	if checksum(b[14:34], 0) != 0 {
+		t.Fatal("invalid IP header checksum")
+	}
+	udp := b[34:]
+	sum := sum16(b[26:34], 0) + protocolUDP + uint32(len(udp))
+	if checksum(udp, sum) != 0 {
+		t.Fatal("invalid UDP checksum")
+	}
+
+	x, err := DecodeFrame(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(x.SrcMAC, f.SrcMAC) || !x.DstIP.Equal(f.DstIP) ||
+		x.SrcPort != f.SrcPort || x.DstPort != f.DstPort ||
+		!bytes.Equal(x.Payload, f.Payload) {
+		t.Fatalf("expect %v, got %v", f, x)
+	}
+	if !x.IsDHCP() {
+		t.Fatal("frame not recognized as DHCP")
+	}
+}
+
+func TestFrameVLAN(t *testing.T) {
+	f := &Frame{
+		SrcMAC:  net.HardwareAddr{1, 2, 3, 4, 5, 6},
+		DstMAC:  net.HardwareAddr{6, 5, 4, 3, 2, 1},
+		SrcIP:   net.IPv4(10, 0, 0, 1),
+		DstIP:   net.IPv4(10, 0, 0, 2),
+		SrcPort: ServerPort,
+		DstPort: ClientPort,
+		Payload: []byte{1, 2, 3},
+	}
+
+	b := EncodeFrame(f)
+	tagged := append([]byte{}, b[:12]...)
+	tagged = append(tagged, 0x81, 0x00, 0x00, 0x0a)
+	tagged = append(tagged, b[12:]...)
+
+	x, err := DecodeFrame(tagged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !x.SrcIP.Equal(f.SrcIP) || !bytes.Equal(x.Payload, f.Payload) {
+		t.Fatalf("expect %v, got %v", f, x)
+	}
+}
+
+func TestFrameNotUDP(t *testing.T) {
+	b := EncodeFrame(&Frame{
+		SrcMAC: make(net.HardwareAddr, 6),
+		DstMAC: make(net.HardwareAddr, 6),
+		SrcIP:  net.IPv4zero,
+		DstIP:  net.IPv4zero,
+	})
+	b[14+9] = 6 // TCP
+	if _, err := DecodeFrame(b); err != ErrNotUDP {
+		t.Fatalf("expect %v, got %v", ErrNotUDP, err)
+	}
+}
@@ -0,0 +1,235 @@
+package dhcp
+
+import (
+	"bytes"
+	"sort"
+	"time"
+)
+
+// Server behaviors
+const (
+	fillsSname    = 1 << iota // server host name in the sname field
+	fillsFile                 // boot file name in the file field
+	copiesFlags               // broadcast flag copied from the request
+	sortedOptions             // options after the message type sorted by code
+)
+
+// serverProfile describes the replies sent by a DHCP server
+// implementation in its default configuration.
+type serverProfile struct {
+	name   string
+	order  []byte   // first options in replies, in order
+	lease  []uint32 // default lease times, in seconds
+	sends  []byte   // options always sent
+	omits  []byte   // options never sent by default
+	echoes []byte   // request options copied to replies
+	set    int      // behaviors present
+	clear  int      // behaviors absent
+}
+
+var serverProfiles = []serverProfile{
+	{
+		name:  "ISC dhcpd",
+		order: []byte{DHCPMessageType, ServerIdentifier, IPAddressLeaseTime, SubnetMask},
+		lease: []uint32{43200},
+		omits: []byte{RenewalTimeValue, RebindingTimeValue},
+		set:   copiesFlags,
+		clear: fillsSname | fillsFile | sortedOptions,
+	},
+	{
+		name:   "Kea",
+		order:  []byte{DHCPMessageType, SubnetMask},
+		lease:  []uint32{7200, 4000},
+		sends:  []byte{RenewalTimeValue, RebindingTimeValue},
+		echoes: []byte{ClientIdentifier},
+		set:    copiesFlags | sortedOptions,
+		clear:  fillsSname | fillsFile,
+	},
+	{
+		name:  "dnsmasq",
+		order: []byte{DHCPMessageType, ServerIdentifier, IPAddressLeaseTime, RenewalTimeValue, RebindingTimeValue},
+		lease: []uint32{3600},
+		sends: []byte{RenewalTimeValue, RebindingTimeValue, BroadcastAddress},
+		set:   copiesFlags,
+		clear: fillsSname | fillsFile | sortedOptions,
+	},
+	{
+		name:   "Windows Server",
+		order:  []byte{DHCPMessageType, SubnetMask, RenewalTimeValue, RebindingTimeValue, IPAddressLeaseTime, ServerIdentifier},
+		lease:  []uint32{691200},
+		sends:  []byte{RenewalTimeValue, RebindingTimeValue},
+		echoes: []byte{ClientFQDN},
+		set:    copiesFlags,
+		clear:  fillsSname | fillsFile | sortedOptions,
+	},
+	{
+		name:  "MikroTik RouterOS",
+		order: []byte{DHCPMessageType, ServerIdentifier, IPAddressLeaseTime, SubnetMask},
+		lease: []uint32{600, 1800},
+		omits: []byte{RenewalTimeValue, RebindingTimeValue},
+		clear: fillsSname | fillsFile | sortedOptions,
+	},
+	{
+		name:  "udhcpd",
+		order: []byte{DHCPMessageType, ServerIdentifier, IPAddressLeaseTime},
+		lease: []uint32{864000},
+		omits: []byte{RenewalTimeValue, RebindingTimeValue},
+		clear: sortedOptions,
+	},
+	{
+		name:  "Consumer router",
+		order: []byte{DHCPMessageType, ServerIdentifier, IPAddressLeaseTime},
+		lease: []uint32{86400, 604800},
+		clear: fillsSname | fillsFile | sortedOptions,
+	},
+}
+
+// ServerGuess is the likely implementation of the server sending a reply.
+type ServerGuess struct {
+	Name       string
+	Confidence float64 // from 0 to 1
+}
+
+// GuessServer compares a reply with the replies sent by known server
+// implementations in their default configuration. The request is used to
+// find echoed options and flags, and may be nil. An empty name is returned
+// if no implementation is similar enough.
+func GuessServer(reply, req *Packet) ServerGuess {
+	var best ServerGuess
+	for i := range serverProfiles {
+		c := serverProfiles[i].compare(reply, req)
+		if c > best.Confidence {
+			best = ServerGuess{serverProfiles[i].name, c}
+		}
+	}
+	if best.Confidence < 0.5 {
+		return ServerGuess{}
+	}
+	return best
+}
+
+// compare returns the weight of the traits of the profile found in the
+// reply, relative to the weight of all traits in the profile.
+func (sp *serverProfile) compare(reply, req *Packet) float64 {
+	opts, _ := reply.DecodeOptions()
+	var codes []byte
+	for _, o := range opts {
+		if o.Type != EndOption {
+			codes = append(codes, o.Type)
+		}
+	}
+	has := func(c byte) bool {
+		return bytes.IndexByte(codes, c) >= 0
+	}
+
+	total, score := 0, 0
+	trait := func(weight int, match bool) {
+		total += weight
+		if match {
+			score += weight
+		}
+	}
+
+	if sp.order != nil {
+		trait(3, bytes.HasPrefix(codes, sp.order))
+	}
+
+	if sp.lease != nil {
+		var match bool
+		t := uint32(reply.LeaseTime() / time.Second)
+		for _, x := range sp.lease {
+			match = match || t == x
+		}
+		trait(2, match)
+	}
+
+	if sp.sends != nil {
+		match := true
+		for _, c := range sp.sends {
+			match = match && has(c)
+		}
+		trait(1, match)
+	}
+
+	if sp.omits != nil {
+		match := true
+		for _, c := range sp.omits {
+			match = match && !has(c)
+		}
+		trait(1, match)
+	}
+
+	if sp.echoes != nil {
+		var asked, echoed int
+		for _, c := range sp.echoes {
+			if req == nil {
+				break
+			}
+			if _, ok := req.Option(c); ok {
+				asked++
+				if has(c) {
+					echoed++
+				}
+			}
+		}
+		// can't tell if the request lacks the options
+		if asked > 0 {
+			trait(2, echoed == asked)
+		} else {
+			total += 2
+		}
+	}
+
+	seen, known := serverBehaviors(reply, req, codes)
+	for _, b := range []int{fillsSname, fillsFile, copiesFlags, sortedOptions} {
+		switch {
+		case (sp.set|sp.clear)&b == 0:
+			continue
+		case known&b == 0:
+			total++
+		default:
+			trait(1, seen&b == sp.set&b)
+		}
+	}
+
+	if total == 0 {
+		return 0
+	}
+	return float64(score) / float64(total)
+}
+
+// serverBehaviors returns the behaviors seen in a reply, and the ones
+// that can be told from the packets.
+func serverBehaviors(reply, req *Packet, codes []byte) (int, int) {
+	var b int
+	known := fillsSname | fillsFile | sortedOptions
+
+	var overload byte
+	if o, ok := reply.Option(OptionOverload); ok && len(o) == 1 {
+		overload = o[0]
+	}
+	if reply.Sname[0] != 0 && overload&OverloadSname == 0 {
+		b |= fillsSname
+	}
+	if reply.File[0] != 0 && overload&OverloadFile == 0 {
+		b |= fillsFile
+	}
+
+	if req != nil {
+		known |= copiesFlags
+		if reply.Flags&FlagBroadcast == req.Flags&FlagBroadcast {
+			b |= copiesFlags
+		}
+	}
+
+	if len(codes) > 2 && codes[0] == DHCPMessageType {
+		rest := bytes.TrimSuffix(codes[1:], []byte{RelayAgentInformation})
+		if sort.SliceIsSorted(rest, func(i, j int) bool {
+			return rest[i] < rest[j]
+		}) {
+			b |= sortedOptions
+		}
+	}
+
+	return b, known
+}
@@ -0,0 +1,69 @@
+package dhcp
+
+import (
+	"net"
+	"testing"
+)
+
+func newTestReply(codes []byte, lease uint32) *Packet {
+	p := &Packet{Op: BootReply, Magic: magic, Options: OptionsArea{EndOption}}
+	for _, c := range codes {
+		switch c {
+		case DHCPMessageType:
+			p.SetOption(c, []byte{DHCPOffer})
+		case IPAddressLeaseTime:
+			p.SetUint32(c, lease)
+		case RenewalTimeValue:
+			p.SetUint32(c, lease/2)
+		case RebindingTimeValue:
+			p.SetUint32(c, lease/8*7)
+		case SubnetMask:
+			p.SetIP(c, net.IPv4(255, 255, 255, 0))
+		default:
+			p.SetIP(c, net.IPv4(192, 168, 0, 1))
+		}
+	}
+	return p
+}
+
+func TestGuessServer(t *testing.T) {
+	tests := []struct {
+		codes []byte
+		lease uint32
+		want  string
+	}{
+		{[]byte{53, 54, 51, 58, 59, 1, 28, 3, 6}, 3600, "dnsmasq"},
+		{[]byte{53, 1, 58, 59, 51, 54, 3, 6, 15}, 691200, "Windows Server"},
+		{[]byte{53, 1, 3, 6, 51, 54, 58, 59}, 4000, "Kea"},
+		{[]byte{53, 54, 51, 1, 3, 6}, 43200, "ISC dhcpd"},
+		{[]byte{53, 54, 51, 1, 3, 6}, 864000, "udhcpd"},
+		{[]byte{1, 3, 53}, 100, ""},
+	}
+
+	for _, tt := range tests {
+		g := GuessServer(newTestReply(tt.codes, tt.lease), nil)
+		if g.Name != tt.want {
+			t.Errorf("%v %d: expect %q, got %q (%.2f)", tt.codes, tt.lease,
+				tt.want, g.Name, g.Confidence)
+		}
+	}
+}
+
+func TestGuessServerRequest(t *testing.T) {
+	reply := newTestReply([]byte{53, 1, 58, 59, 51, 54, 3, 6, 15}, 691200)
+	reply.Flags = FlagBroadcast
+	req := NewDiscoverPacket()
+	req.SetOption(ClientFQDN, []byte{0, 0, 0, 'p', 'c'})
+
+	without := GuessServer(reply, req)
+	if without.Name != "Windows Server" {
+		t.Fatalf("expect Windows Server, got %q", without.Name)
+	}
+
+	reply.SetOption(ClientFQDN, []byte{0, 0, 0, 'p', 'c'})
+	with := GuessServer(reply, req)
+	if with.Name != "Windows Server" || with.Confidence <= without.Confidence {
+		t.Fatalf("expect higher confidence with echoed FQDN, got %.2f, %.2f",
+			without.Confidence, with.Confidence)
+	}
+}
@@ -1,15 +1,26 @@
 package dhcp
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"time"
 )
 
+// A Peer sends and receives DHCP packets. Blocking calls return when the
+// context is done, with the context error.
 type Peer interface {
-	Send(*Packet) error
-	Receive(time.Duration) (Packet, *net.UDPAddr, error)
-	Broadcast(*Packet) error
+	Send(context.Context, *Packet) error
+	Receive(context.Context) (Packet, *net.UDPAddr, error)
+	Broadcast(context.Context, *Packet) error
+	Close() error
+}
+
+// A LinkPeer is a Peer that knows the hardware address of the sender of
+// the last packet received.
+type LinkPeer interface {
+	Peer
+	RemoteMAC() net.HardwareAddr
 }
 
 // Peer definitions
@@ -61,23 +72,37 @@ func (pr *peer) setRemote(ip net.IP) error {
 	return nil
 }
 
-func (pr *peer) Close() {
-	pr.local.Close()
+func (pr *peer) Close() error {
+	var err error
+	if pr.local != nil {
+		err = pr.local.Close()
+	}
+	if pr.remote != nil {
+		pr.remote.Close()
+	}
+	return err
 }
 
 func (pr *peer) closeRemote() {
 	pr.remote.Close()
+	pr.remote = nil
 }
 
-func (pr *peer) Send(p *Packet) error {
-	return send(pr.remote, p)
+func (pr *peer) Send(ctx context.Context, p *Packet) error {
+	if pr.remote == nil {
+		return fmt.Errorf("dhcp: remote address not set")
+	}
+	return send(ctx, pr.remote, p)
 }
 
-func (pr *peer) Receive(timeout time.Duration) (Packet, *net.UDPAddr, error) {
-	return receive(pr.local, timeout)
+func (pr *peer) Receive(ctx context.Context) (Packet, *net.UDPAddr, error) {
+	if pr.local == nil {
+		return Packet{}, nil, fmt.Errorf("dhcp: peer not listening")
+	}
+	return receive(ctx, pr.local)
 }
 
-func (pr *peer) Broadcast(p *Packet) error {
+func (pr *peer) Broadcast(ctx context.Context, p *Packet) error {
 	addr, err := net.ResolveUDPAddr("udp4",
 		fmt.Sprintf("%s:%d", net.IPv4bcast.String(), pr.remotePort))
 	if err != nil {
@@ -90,7 +115,7 @@ func (pr *peer) Broadcast(p *Packet) error {
 	}
 	defer conn.Close()
 
-	return send(conn, p)
+	return send(ctx, conn, p)
 }
 
 func (pr *peer) Address() string {
@@ -104,7 +129,7 @@ type Client struct {
 }
 
 func NewClient() (*Client, error) {
-	pr, err := newPeer(68, 67, true)
+	pr, err := newPeer(ClientPort, ServerPort, true)
 	if pr == nil {
 		return nil, err
 	}
@@ -112,7 +137,7 @@ func NewClient() (*Client, error) {
 }
 
 func NewClientNotListening() (*Client, error) {
-	pr, err := newPeer(68, 67, false)
+	pr, err := newPeer(ClientPort, ServerPort, false)
 	if pr == nil {
 		return nil, err
 	}
@@ -127,6 +152,28 @@ func (cl *Client) CloseServer() {
 	cl.closeRemote()
 }
 
+// SendTo sends a packet to a server from the client port. Unlike Send, it
+// doesn't use a separate socket bound to an ephemeral port, which some
+// servers and relay agents drop. The client must be listening.
+func (cl *Client) SendTo(ctx context.Context, p *Packet, svIP net.IP) error {
+	if cl.local == nil {
+		return fmt.Errorf("dhcp: peer not listening")
+	}
+	data, err := p.Encode(0)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	deadline, _ := ctx.Deadline()
+	cl.local.SetWriteDeadline(deadline)
+
+	_, err = cl.local.WriteToUDP(data, &net.UDPAddr{IP: svIP, Port: cl.remotePort})
+	return err
+}
+
 // Server
 
 type Server struct {
@@ -134,13 +181,27 @@ type Server struct {
 }
 
 func NewServer() (*Server, error) {
-	pr, err := newPeer(67, 68, true)
+	pr, err := newPeer(ServerPort, ClientPort, true)
 	if pr == nil {
 		return nil, err
 	}
 	return &Server{*pr}, err
 }
 
+// Reply sends a reply to a request received from a client, honoring the
+// maximum message size set by the client.
+func (sv *Server) Reply(ctx context.Context, p, req *Packet) error {
+	if sv.remote == nil {
+		return fmt.Errorf("dhcp: remote address not set")
+	}
+	data, err := p.Encode(req.MaxMessageSize())
+	if err != nil {
+		return err
+	}
+
+	return write(ctx, sv.remote, data)
+}
+
 func (sv *Server) SetClient(clIP net.IP) error {
 	return sv.setRemote(clIP)
 }
@@ -151,29 +212,53 @@ func (sv *Server) CloseClient() {
 
 // Helpers
 
-func send(conn net.Conn, p *Packet) error {
-	data, err := p.serialize()
+func send(ctx context.Context, conn net.Conn, p *Packet) error {
+	data, err := p.Encode(0)
 	if err != nil {
 		return err
 	}
 
-	_, err = conn.Write(data)
+	return write(ctx, conn, data)
+}
+
+func write(ctx context.Context, conn net.Conn, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	deadline, _ := ctx.Deadline()
+	conn.SetWriteDeadline(deadline)
+
+	_, err := conn.Write(data)
 
 	return err
 }
 
-func receive(conn *net.UDPConn, timeout time.Duration) (Packet, *net.UDPAddr, error) {
+func receive(ctx context.Context, conn *net.UDPConn) (Packet, *net.UDPAddr, error) {
 	var p Packet
-	b := make([]byte, 1500)
-	if timeout > 0 {
-		conn.SetReadDeadline(time.Now().Add(timeout))
+
+	if err := ctx.Err(); err != nil {
+		return p, nil, err
 	}
-	_, remote, err := conn.ReadFromUDP(b)
+
+	// unblock the read when the context is done
+	deadline, _ := ctx.Deadline()
+	conn.SetReadDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetReadDeadline(time.Now())
+	})
+	defer stop()
+
+	b := make([]byte, maxPacketSize)
+	n, remote, err := conn.ReadFromUDP(b)
 	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
 		return p, remote, err
 	}
 
-	err = p.deserialize(b)
+	err = p.Decode(b[:n])
 
 	return p, remote, err
 }
@@ -0,0 +1,26 @@
+package dhcp
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestReplyNoClient(t *testing.T) {
+	var sv Server
+	req := NewDiscoverPacket()
+	if err := sv.Reply(context.Background(), NewDiscoverPacket(), req); err == nil {
+		t.Fatal("reply sent without client address")
+	}
+}
+
+func TestSendToNotListening(t *testing.T) {
+	cl, err := NewClientNotListening()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+	if err := cl.SendTo(context.Background(), NewDiscoverPacket(), net.IPv4(127, 0, 0, 1)); err == nil {
+		t.Fatal("packet sent without local socket")
+	}
+}
@@ -1,7 +1,11 @@
 package dhcp
 
 import (
+	"encoding/binary"
 	"errors"
+	"fmt"
+	"net"
+	"time"
 )
 
 const (
@@ -101,16 +105,203 @@ const (
 	// RFC 4702
 	ClientFQDN = 81
 
+	// RFC 3046
+	RelayAgentInformation = 82
+
 	// RFC 3397
 	DomainSearch = 119
 
+	// RFC 3442
+	ClasslessStaticRoute = 121
+
+	// Microsoft Classless Static Route
+	MSClasslessStaticRoute = 249
+
 	// Web Proxy Auto-Discovery Protocol (ietf-wrec-wpad-01)
 	WebProxyServer = 252
 )
 
-var ErrCorruptedOptions = errors.New("dhcp: corrupted options data")
+// Option Overload values
+const (
+	OverloadFile  = 1
+	OverloadSname = 2
+)
+
+var (
+	ErrCorruptedOptions = errors.New("dhcp: corrupted options data")
+	ErrOptionsFull      = errors.New("dhcp: options area full")
+)
 
 type Option struct {
 	Type byte
 	Data []byte
 }
+
+// Option returns the data of the option with the given code, and whether
+// the option is present in the packet.
+func (p *Packet) Option(code byte) ([]byte, bool) {
+	opts, _ := p.DecodeOptions()
+	for _, o := range opts {
+		if o.Type == code {
+			return o.Data, true
+		}
+	}
+	return nil, false
+}
+
+// SetOption adds an option to the packet, replacing any existing option
+// with the same code. Values longer than 255 bytes are split into multiple
+// instances of the option (RFC 3396).
+func (p *Packet) SetOption(code byte, value []byte) error {
+	opts, err := p.otherOptions(code)
+	if err != nil {
+		return err
+	}
+
+	return p.setOptions(append(opts, Option{code, value}))
+}
+
+// RemoveOption removes the option with the given code from the packet.
+func (p *Packet) RemoveOption(code byte) error {
+	opts, err := p.otherOptions(code)
+	if err != nil {
+		return err
+	}
+
+	return p.setOptions(opts)
+}
+
+// SetIP sets an option containing a single IPv4 address.
+func (p *Packet) SetIP(code byte, ip net.IP) error {
+	return p.SetIPs(code, []net.IP{ip})
+}
+
+// SetIPs sets an option containing a list of IPv4 addresses.
+func (p *Packet) SetIPs(code byte, ips []net.IP) error {
+	var b []byte
+	for _, ip := range ips {
+		ip4 := ip.To4()
+		if ip4 == nil {
+			return fmt.Errorf("dhcp: %s: not an IPv4 address", ip)
+		}
+		b = append(b, ip4...)
+	}
+	return p.SetOption(code, b)
+}
+
+// SetUint32 sets an option containing a 32-bit integer.
+func (p *Packet) SetUint32(code byte, v uint32) error {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return p.SetOption(code, b)
+}
+
+// SetString sets an option containing a string.
+func (p *Packet) SetString(code byte, s string) error {
+	return p.SetOption(code, []byte(s))
+}
+
+// MessageType returns the DHCP message type, or zero if the packet is not
+// a DHCP message.
+func (p *Packet) MessageType() byte {
+	if b, ok := p.Option(DHCPMessageType); ok && len(b) == 1 {
+		return b[0]
+	}
+	return 0
+}
+
+// ServerID returns the server identifier, or nil if not present.
+func (p *Packet) ServerID() net.IP {
+	if b, ok := p.Option(ServerIdentifier); ok && len(b) == 4 {
+		return net.IPv4(b[0], b[1], b[2], b[3])
+	}
+	return nil
+}
+
+// LeaseTime returns the IP address lease time, or zero if not present.
+func (p *Packet) LeaseTime() time.Duration {
+	if b, ok := p.Option(IPAddressLeaseTime); ok && len(b) == 4 {
+		return time.Duration(binary.BigEndian.Uint32(b)) * time.Second
+	}
+	return 0
+}
+
+// decodeSubOptions parses encapsulated sub-options, such as the ones in
+// the Relay Agent Information and Vendor Specific options. Sub-options
+// have no Pad or End codes.
+func decodeSubOptions(b []byte) ([]Option, error) {
+	var list []Option
+	for i := 0; i < len(b); {
+		if i+2 > len(b) || i+2+int(b[i+1]) > len(b) {
+			return list, ErrCorruptedOptions
+		}
+		l := int(b[i+1])
+		list = append(list, Option{b[i], b[i+2 : i+2+l]})
+		i += 2 + l
+	}
+	return list, nil
+}
+
+// encodeSubOptions encodes a list of sub-options.
+func encodeSubOptions(opts []Option) ([]byte, error) {
+	var b []byte
+	for _, o := range opts {
+		if len(o.Data) > 255 {
+			return nil, ErrInvalidOption
+		}
+		b = append(b, o.Type, byte(len(o.Data)))
+		b = append(b, o.Data...)
+	}
+	return b, nil
+}
+
+// otherOptions returns all options in the packet except the End, Pad,
+// Option Overload and the given option.
+func (p *Packet) otherOptions(code byte) ([]Option, error) {
+	opts, err := p.DecodeOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	var list []Option
+	for _, o := range opts {
+		switch o.Type {
+		case code, PadOption, EndOption, OptionOverload:
+			continue
+		}
+		list = append(list, o)
+	}
+	return list, nil
+}
+
+// setOptions replaces the options area contents with the given options.
+// The packet is left unchanged if the options don't fit. Options from
+// overloaded file and sname fields are moved to the options area.
+func (p *Packet) setOptions(opts []Option) error {
+	var area OptionsArea
+	i := 0
+	for _, o := range splitOptions(opts) {
+		if i+2+len(o.Data) >= len(area) {
+			return ErrOptionsFull
+		}
+		area[i] = o.Type
+		area[i+1] = byte(len(o.Data))
+		copy(area[i+2:], o.Data)
+		i += 2 + len(o.Data)
+	}
+	area[i] = EndOption
+
+	// overloaded options were moved back to the options area
+	if b, ok := p.Option(OptionOverload); ok && len(b) == 1 {
+		if b[0]&OverloadFile != 0 {
+			p.File = [128]byte{}
+		}
+		if b[0]&OverloadSname != 0 {
+			p.Sname = [64]byte{}
+		}
+	}
+
+	p.Options = area
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package dhcp
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSetOption(t *testing.T) {
+	p := NewDiscoverPacket()
+	p.SetString(HostName, "foo")
+	p.SetString(HostName, "bar")
+
+	b, ok := p.Option(HostName)
+	if !ok || string(b) != "bar" {
+		t.Fatalf("expect %q, got %q", "bar", b)
+	}
+	if p.MessageType() != DHCPDiscover {
+		t.Fatalf("expect %d, got %d", DHCPDiscover, p.MessageType())
+	}
+}
+
+func TestRemoveOption(t *testing.T) {
+	p := NewDiscoverPacket()
+	p.SetString(HostName, "foo")
+	p.RemoveOption(HostName)
+
+	if _, ok := p.Option(HostName); ok {
+		t.Fatal("option not removed")
+	}
+	expect := []byte{DHCPMessageType, 1, DHCPDiscover, EndOption, 0}
+	if !bytes.Equal(p.Options[:5], expect) {
+		t.Fatalf("expect %v, got %v", expect, p.Options[:5])
+	}
+}
+
+func TestTypedOptions(t *testing.T) {
+	p := NewDiscoverPacket()
+	p.SetIP(ServerIdentifier, net.IPv4(10, 0, 0, 1))
+	p.SetUint32(IPAddressLeaseTime, 3600)
+
+	if ip := p.ServerID(); !ip.Equal(net.IPv4(10, 0, 0, 1)) {
+		t.Fatalf("expect 10.0.0.1, got %s", ip)
+	}
+	if d := p.LeaseTime(); d != time.Hour {
+		t.Fatalf("expect %s, got %s", time.Hour, d)
+	}
+	if err := p.SetIP(Router, net.ParseIP("::1")); err == nil {
+		t.Fatal("IPv6 address accepted")
+	}
+}
+
+func TestAddOptionsFull(t *testing.T) {
+	p := NewDiscoverPacket()
+	if err := p.AddOptions(make([]byte, len(p.Options))); err != ErrOptionsFull {
+		t.Fatalf("expect %v, got %v", ErrOptionsFull, err)
+	}
+}
+
+func TestLongOptionEncode(t *testing.T) {
+	p := NewDiscoverPacket()
+	data := make([]byte, 300)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	p.SetOption(VendorSpecific, data)
+
+	if p.Options[3] != VendorSpecific || p.Options[4] != 255 {
+		t.Fatalf("expect first instance with 255 bytes, got %v", p.Options[3:5])
+	}
+	if p.Options[260] != VendorSpecific || p.Options[261] != 45 {
+		t.Fatalf("expect second instance with 45 bytes, got %v", p.Options[260:262])
+	}
+
+	b, ok := p.Option(VendorSpecific)
+	if !ok || !bytes.Equal(b, data) {
+		t.Fatalf("expect %v, got %v", data, b)
+	}
+}
+
+func TestLongOptionDecode(t *testing.T) {
+	p := NewDiscoverPacket()
+	p.AddOptions([]byte{DomainSearch, 2, 'a', 'b', HostName, 1, 'x',
+		DomainSearch, 1, 'c'})
+
+	opts, err := p.DecodeOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opts) != 4 {
+		t.Fatalf("expect 4 options, got %d", len(opts))
+	}
+	if o := opts[1]; o.Type != DomainSearch || string(o.Data) != "abc" {
+		t.Fatalf("expect %q, got %q", "abc", o.Data)
+	}
+	if o := opts[2]; o.Type != HostName || string(o.Data) != "x" {
+		t.Fatalf("expect %q, got %q", "x", o.Data)
+	}
+
+	// packet data must be unchanged
+	if p.Options[7] != HostName {
+		t.Fatalf("options area modified: %v", p.Options[:13])
+	}
+}
+
+func TestLongOptionOverload(t *testing.T) {
+	p := NewDiscoverPacket()
+	data := make([]byte, 400)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	p.SetOption(VendorSpecific, data)
+
+	b, err := p.Encode(packetSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var q Packet
+	if err := q.Decode(b); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := q.Option(VendorSpecific); !ok || !bytes.Equal(v, data) {
+		t.Fatalf("expect %v, got %v", data, v)
+	}
+}
@@ -1,8 +1,8 @@
 package dhcp
 
 import (
-	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net"
@@ -30,11 +30,20 @@ const (
 )
 
 const (
-	packetSize    = 548
+	headerSize    = 236  // BOOTP fixed fields, up to the end of file
+	minPacketSize = 300  // minimum BOOTP message size (RFC 951)
+	packetSize    = 548  // maximum message size a client must accept
+	maxPacketSize = 1500 // receive buffer size
+	ipUDPSize     = 28   // IPv4 and UDP header sizes
 	magic         = 0x63825363
 	HtypeEthernet = 1
 )
 
+var (
+	ErrShortPacket    = errors.New("dhcp: packet too short")
+	ErrPacketTooLarge = errors.New("dhcp: packet exceeds maximum message size")
+)
+
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }
@@ -60,7 +69,9 @@ func (a *HWAddress) MACAddress() *MACAddress {
 	return &mac
 }
 
-type OptionsArea [1200]byte
+// OptionsArea holds the options of the largest message that can be
+// received.
+type OptionsArea [maxPacketSize - headerSize - 4]byte
 
 type Packet struct {
 	Op      byte
@@ -81,14 +92,126 @@ type Packet struct {
 	Options OptionsArea
 }
 
-func (p Packet) serialize() ([]byte, error) {
-	var buf bytes.Buffer
-	err := binary.Write(&buf, binary.BigEndian, p)
-	return buf.Bytes(), err
+// Encode returns the wire format of the packet. Only the options up to
+// the End option are written, and the message is padded to the minimum
+// BOOTP message size. If max is greater than zero and the options don't
+// fit in max bytes, they are overloaded into the file and sname fields if
+// these are unused; otherwise ErrPacketTooLarge is returned.
+func (p *Packet) Encode(max int) ([]byte, error) {
+	n := p.optionsLen()
+	size := headerSize + 4 + n
+	if max > 0 && size > max {
+		q, err := p.overload(max - headerSize - 4)
+		if err != nil {
+			return nil, err
+		}
+		return q.Encode(max)
+	}
+	if size < minPacketSize {
+		size = minPacketSize
+	}
+	if max > 0 && size > max {
+		return nil, ErrPacketTooLarge
+	}
+
+	b := make([]byte, size)
+	b[0] = p.Op
+	b[1] = p.Htype
+	b[2] = p.Hlen
+	b[3] = p.Hops
+	binary.BigEndian.PutUint32(b[4:], p.Xid)
+	binary.BigEndian.PutUint16(b[8:], p.Secs)
+	binary.BigEndian.PutUint16(b[10:], p.Flags)
+	copy(b[12:], p.Ciaddr[:])
+	copy(b[16:], p.Yiaddr[:])
+	copy(b[20:], p.Siaddr[:])
+	copy(b[24:], p.Giaddr[:])
+	copy(b[28:], p.Chaddr[:])
+	copy(b[44:], p.Sname[:])
+	copy(b[108:], p.File[:])
+	binary.BigEndian.PutUint32(b[headerSize:], p.Magic)
+	copy(b[headerSize+4:], p.Options[:n])
+
+	return b, nil
+}
+
+// Decode parses a DHCP message received from the network. The options
+// area is filled with the options present in the message and zeroed
+// after them.
+func (p *Packet) Decode(data []byte) error {
+	if len(data) < headerSize+4 {
+		return ErrShortPacket
+	}
+
+	p.Op = data[0]
+	p.Htype = data[1]
+	p.Hlen = data[2]
+	p.Hops = data[3]
+	p.Xid = binary.BigEndian.Uint32(data[4:])
+	p.Secs = binary.BigEndian.Uint16(data[8:])
+	p.Flags = binary.BigEndian.Uint16(data[10:])
+	copy(p.Ciaddr[:], data[12:])
+	copy(p.Yiaddr[:], data[16:])
+	copy(p.Siaddr[:], data[20:])
+	copy(p.Giaddr[:], data[24:])
+	copy(p.Chaddr[:], data[28:])
+	copy(p.Sname[:], data[44:])
+	copy(p.File[:], data[108:])
+	p.Magic = binary.BigEndian.Uint32(data[headerSize:])
+
+	p.Options = OptionsArea{}
+	if copy(p.Options[:], data[headerSize+4:]) < len(data)-headerSize-4 {
+		return ErrPacketTooLarge
+	}
+
+	return nil
+}
+
+// MaxMessageSize returns the size of the largest DHCP message the sender
+// of the packet is willing to accept, as set in the Max DHCP Message Size
+// option. If the option is not present, the RFC 2131 default is used.
+func (p *Packet) MaxMessageSize() int {
+	opts, _ := p.DecodeOptions()
+	for _, o := range opts {
+		if o.Type != MaxDHCPMessageSize || len(o.Data) != 2 {
+			continue
+		}
+		// option value includes IP and UDP headers
+		n := int(binary.BigEndian.Uint16(o.Data)) - ipUDPSize
+		if n > packetSize {
+			return n
+		}
+		break
+	}
+	return packetSize
 }
 
-func (p *Packet) deserialize(data []byte) error {
-	return binary.Read(bytes.NewReader(data), binary.BigEndian, p)
+// optionsLen returns the length of the options area up to and including
+// the End option.
+func (p *Packet) optionsLen() int {
+	last := 0
+	for i := 0; i < len(p.Options); {
+		o := p.Options[i]
+		i++
+
+		if o == EndOption {
+			return i
+		}
+		if o == PadOption {
+			continue
+		}
+		if i >= len(p.Options) {
+			return len(p.Options)
+		}
+
+		i += 1 + int(p.Options[i])
+		last = i
+	}
+
+	if last > len(p.Options) {
+		return len(p.Options)
+	}
+	return last
 }
 
 // SetClientMAC takes a MAC address and sets the client hardware address
@@ -101,85 +224,255 @@ func (p *Packet) SetClientMAC(mac string) error {
 	return err
 }
 
-// NewDiscoverPacket builds a new DHCPDISCOVER packet.
-func NewDiscoverPacket() *Packet {
+// IP returns the address as a net.IP.
+func (a *IPv4Address) IP() net.IP {
+	return net.IPv4(a[0], a[1], a[2], a[3])
+}
+
+// newRequestPacket builds a new client packet of the given message type.
+func newRequestPacket(t byte) *Packet {
 	p := &Packet{
-		Op:    BootRequest,
-		Htype: HtypeEthernet,
-		Hlen:  6,
-		Hops:  0,
-		Xid:   rand.Uint32(),
-		Secs:  0,
-		Flags: FlagBroadcast,
-		Magic: magic,
-		Options: OptionsArea{DHCPMessageType, 1, DHCPDiscover,
-			EndOption},
+		Op:      BootRequest,
+		Htype:   HtypeEthernet,
+		Hlen:    6,
+		Hops:    0,
+		Xid:     rand.Uint32(),
+		Secs:    0,
+		Flags:   FlagBroadcast,
+		Magic:   magic,
+		Options: OptionsArea{DHCPMessageType, 1, t, EndOption},
 	}
 
 	return p
 }
 
-func (p *Packet) DecodeOptions() ([]Option, error) {
+// NewDiscoverPacket builds a new DHCPDISCOVER packet.
+func NewDiscoverPacket() *Packet {
+	return newRequestPacket(DHCPDiscover)
+}
 
-	var option []Option
+// NewRequestPacket builds a DHCPREQUEST packet accepting an offer, with
+// the same transaction ID and client hardware address.
+func NewRequestPacket(offer *Packet) *Packet {
+	p := newRequestPacket(DHCPRequest)
+	p.Xid = offer.Xid
+	p.Chaddr = offer.Chaddr
+	p.SetIP(RequestedIPAddress, offer.Yiaddr.IP())
+	if id := offer.ServerID(); id != nil {
+		p.SetIP(ServerIdentifier, id)
+	}
 
-	for i := 0; i < len(p.Options); {
+	return p
+}
 
-		if i >= len(p.Options) {
-			return option, ErrCorruptedOptions
-		}
+// NewReleasePacket builds a DHCPRELEASE packet releasing the address
+// assigned in an acknowledgement.
+func NewReleasePacket(ack *Packet) *Packet {
+	p := newRequestPacket(DHCPRelease)
+	p.Flags = 0
+	p.Ciaddr = ack.Yiaddr
+	p.Chaddr = ack.Chaddr
+	if id := ack.ServerID(); id != nil {
+		p.SetIP(ServerIdentifier, id)
+	}
 
-		o := p.Options[i]
-		i++
+	return p
+}
 
-		if o == EndOption || o == PadOption {
-			option = append(option, Option{o, nil})
-			continue
-		}
+// DecodeOptions returns the list of options in the packet, in order.
+// If the Option Overload option is present, options stored in the file
+// and sname fields are decoded after the options area (RFC 2131 section
+// 4.1). Multiple instances of the same option are concatenated (RFC
+// 3396). Pad options are skipped, and the End option is returned as the
+// last element if the options area is properly terminated.
+func (p *Packet) DecodeOptions() ([]Option, error) {
 
-		if i >= len(p.Options) {
-			return option, ErrCorruptedOptions
+	option, end, err := decodeOptions(p.Options[:])
+	if err != nil {
+		return option, err
+	}
+
+	var overload byte
+	for _, o := range option {
+		if o.Type == OptionOverload && len(o.Data) == 1 {
+			overload = o.Data[0]
 		}
+	}
 
-		l := int(p.Options[i])
-		i++
+	if overload&OverloadFile != 0 {
+		opts, _, err := decodeOptions(p.File[:])
+		option = append(option, opts...)
+		if err != nil {
+			return option, err
+		}
+	}
 
-		if i+l > len(p.Options) {
-			return option, ErrCorruptedOptions
+	if overload&OverloadSname != 0 {
+		opts, _, err := decodeOptions(p.Sname[:])
+		option = append(option, opts...)
+		if err != nil {
+			return option, err
 		}
+	}
 
-		option = append(option, Option{o, p.Options[i : i+l]})
+	option = concatOptions(option)
 
-		i += l
+	if end {
+		option = append(option, Option{EndOption, nil})
 	}
 
 	return option, nil
 }
 
-func (p *Packet) AddOptions(b []byte) {
-	// find EndOption
-	var i int
-	for i = 0; i < len(p.Options); {
-
-		o := p.Options[i]
+// concatOptions joins the data of multiple instances of the same option
+// into a single option, as described in RFC 3396.
+func concatOptions(opts []Option) []Option {
+	var list []Option
+	index := map[byte]int{}
+	for _, o := range opts {
+		if i, ok := index[o.Type]; ok {
+			// don't append in place, data points to the packet
+			data := append([]byte{}, list[i].Data...)
+			list[i].Data = append(data, o.Data...)
+			continue
+		}
+		index[o.Type] = len(list)
+		list = append(list, o)
+	}
+	return list
+}
 
-		if o == EndOption {
-			break
+// splitOptions splits options longer than 255 bytes into multiple
+// instances of the same option, as described in RFC 3396.
+func splitOptions(opts []Option) []Option {
+	var list []Option
+	for _, o := range opts {
+		data := o.Data
+		for len(data) > 255 {
+			list = append(list, Option{o.Type, data[:255]})
+			data = data[255:]
 		}
+		list = append(list, Option{o.Type, data})
+	}
+	return list
+}
+
+// decodeOptions parses the options in b up to the End option, and reports
+// whether the End option was found.
+func decodeOptions(b []byte) ([]Option, bool, error) {
+
+	var option []Option
 
+	for i := 0; i < len(b); {
+
+		o := b[i]
 		i++
 
+		if o == EndOption {
+			return option, true, nil
+		}
+
 		if o == PadOption {
 			continue
 		}
 
-		l := int(p.Options[i])
+		if i >= len(b) {
+			return option, false, ErrCorruptedOptions
+		}
+
+		l := int(b[i])
 		i++
 
+		if i+l > len(b) {
+			return option, false, ErrCorruptedOptions
+		}
+
+		option = append(option, Option{o, b[i : i+l]})
+
 		i += l
 	}
 
-	copy(p.Options[i:i+len(b)], b)
+	return option, false, nil
+}
+
+// overload returns a copy of the packet with options spilled into the
+// file and sname fields, so that the options area is not longer than
+// size bytes (RFC 2131 section 4.1).
+func (p *Packet) overload(size int) (*Packet, error) {
+	if _, ok := p.Option(OptionOverload); ok {
+		return nil, ErrPacketTooLarge
+	}
+	if p.File != [128]byte{} || p.Sname != [64]byte{} || size < 4 {
+		return nil, ErrPacketTooLarge
+	}
 
+	opts, err := p.otherOptions(PadOption)
+	if err != nil {
+		return nil, err
+	}
+
+	q := *p
+	q.Options = OptionsArea{OptionOverload, 1, 0}
+
+	areas := [][]byte{q.Options[:size], q.File[:], q.Sname[:]}
+	flags := []byte{0, OverloadFile, OverloadSname}
+
+	n, i := 0, 3
+	for _, o := range opts {
+		// long options can be split across fields (RFC 3396)
+		long := len(o.Data) > 255
+		data := o.Data
+		for {
+			// leave room for the End option
+			avail := len(areas[n]) - i - 3
+			if avail < len(data) && (!long || avail <= 0) {
+				areas[n][i] = EndOption
+				n++
+				i = 0
+				if n >= len(areas) {
+					return nil, ErrPacketTooLarge
+				}
+				continue
+			}
+
+			l := len(data)
+			if l > avail {
+				l = avail
+			}
+			if l > 255 {
+				l = 255
+			}
+			areas[n][i] = o.Type
+			areas[n][i+1] = byte(l)
+			copy(areas[n][i+2:], data[:l])
+			i += 2 + l
+			q.Options[2] |= flags[n]
+
+			data = data[l:]
+			if len(data) == 0 {
+				break
+			}
+		}
+	}
+	areas[n][i] = EndOption
+
+	return &q, nil
+}
+
+// AddOptions appends raw option data to the options area, before the End
+// option.
+func (p *Packet) AddOptions(b []byte) error {
+	i := p.optionsLen()
+	if i > 0 && p.Options[i-1] == EndOption {
+		i--
+	}
+
+	if i+len(b) >= len(p.Options) {
+		return ErrOptionsFull
+	}
+
+	copy(p.Options[i:], b)
 	p.Options[i+len(b)] = EndOption
+
+	return nil
 }
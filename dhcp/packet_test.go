@@ -0,0 +1,192 @@
+package dhcp
+
+import (
+	"net"
+	"testing"
+)
+
+func TestEncodeMinimumSize(t *testing.T) {
+	p := NewDiscoverPacket()
+	b, err := p.Encode(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != minPacketSize {
+		t.Fatalf("expect %d bytes, got %d", minPacketSize, len(b))
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	p := NewDiscoverPacket()
+	p.SetClientMAC("01:02:03:04:05:06")
+	p.Options[3] = HostName
+	p.Options[4] = 3
+	copy(p.Options[5:], "foo")
+	p.Options[8] = EndOption
+
+	b, err := p.Encode(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var q Packet
+	if err := q.Decode(b); err != nil {
+		t.Fatal(err)
+	}
+	if q != *p {
+		t.Fatalf("expect %v, got %v", p, q)
+	}
+}
+
+func TestEncodeTooLarge(t *testing.T) {
+	p := NewDiscoverPacket()
+	p.Options[3] = VendorSpecific
+	p.Options[4] = 255
+	p.Options[260] = VendorSpecific
+	p.Options[261] = 255
+	p.Options[517] = EndOption
+
+	if _, err := p.Encode(packetSize); err != ErrPacketTooLarge {
+		t.Fatalf("expect %v, got %v", ErrPacketTooLarge, err)
+	}
+	if _, err := p.Encode(0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDecodeLarge(t *testing.T) {
+	p := NewDiscoverPacket()
+	b, err := p.Encode(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// largest UDP payload in an Ethernet frame
+	b = append(b, make([]byte, 1472-len(b))...)
+	var q Packet
+	if err := q.Decode(b); err != nil {
+		t.Fatal(err)
+	}
+	if q.MessageType() != DHCPDiscover {
+		t.Fatalf("expect DHCPDISCOVER, got %d", q.MessageType())
+	}
+
+	if err := q.Decode(make([]byte, maxPacketSize+1)); err != ErrPacketTooLarge {
+		t.Fatalf("expect %v, got %v", ErrPacketTooLarge, err)
+	}
+}
+
+func TestDecodeShort(t *testing.T) {
+	var p Packet
+	if err := p.Decode(make([]byte, 239)); err != ErrShortPacket {
+		t.Fatalf("expect %v, got %v", ErrShortPacket, err)
+	}
+}
+
+func TestMaxMessageSize(t *testing.T) {
+	p := NewDiscoverPacket()
+	if n := p.MaxMessageSize(); n != packetSize {
+		t.Fatalf("expect %d, got %d", packetSize, n)
+	}
+
+	copy(p.Options[3:], []byte{MaxDHCPMessageSize, 2, 0x05, 0xdc, EndOption})
+	if n := p.MaxMessageSize(); n != 1472 {
+		t.Fatalf("expect %d, got %d", 1472, n)
+	}
+}
+
+func TestOverload(t *testing.T) {
+	p := NewDiscoverPacket()
+	p.SetOption(VendorSpecific, make([]byte, 200))
+	p.SetOption(NTPServers, make([]byte, 100))
+	p.SetString(HostName, "foo")
+	p.SetString(DomainName, "bar")
+
+	b, err := p.Encode(packetSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) > packetSize {
+		t.Fatalf("expect at most %d bytes, got %d", packetSize, len(b))
+	}
+
+	var q Packet
+	if err := q.Decode(b); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := q.Option(OptionOverload); !ok || v[0] != OverloadFile {
+		t.Fatalf("expect overload %d, got %v", OverloadFile, v)
+	}
+
+	opts, err := q.DecodeOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []byte{OptionOverload, DHCPMessageType, VendorSpecific,
+		NTPServers, HostName, DomainName, EndOption}
+	if len(opts) != len(expect) {
+		t.Fatalf("expect %d options, got %d", len(expect), len(opts))
+	}
+	for i, o := range opts {
+		if o.Type != expect[i] {
+			t.Fatalf("option %d: expect %d, got %d", i, expect[i], o.Type)
+		}
+	}
+
+	// setting an option moves options back to the options area
+	q.SetString(HostName, "baz")
+	if _, ok := q.Option(OptionOverload); ok || q.File != [128]byte{} {
+		t.Fatal("overload not removed")
+	}
+	if v, _ := q.Option(DomainName); string(v) != "bar" {
+		t.Fatalf("expect %q, got %q", "bar", v)
+	}
+}
+
+func TestDecodeOverloadSname(t *testing.T) {
+	p := NewDiscoverPacket()
+	p.SetOption(OptionOverload, []byte{OverloadFile | OverloadSname})
+	copy(p.File[:], []byte{HostName, 3, 'f', 'o', 'o', EndOption})
+	copy(p.Sname[:], []byte{DomainName, 3, 'b', 'a', 'r', EndOption})
+
+	opts, err := p.DecodeOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []byte{DHCPMessageType, OptionOverload, HostName, DomainName,
+		EndOption}
+	if len(opts) != len(expect) {
+		t.Fatalf("expect %d options, got %d", len(expect), len(opts))
+	}
+	for i, o := range opts {
+		if o.Type != expect[i] {
+			t.Fatalf("option %d: expect %d, got %d", i, expect[i], o.Type)
+		}
+	}
+}
+
+func TestRequestPacket(t *testing.T) {
+	o := &Packet{Op: BootReply, Xid: 1234, Yiaddr: IPv4Address{10, 0, 0, 5}}
+	o.SetClientMAC("01:02:03:04:05:06")
+	o.SetIP(ServerIdentifier, net.IPv4(10, 0, 0, 1))
+	o.SetUint32(IPAddressLeaseTime, 3600)
+
+	p := NewRequestPacket(o)
+	if p.Xid != o.Xid || p.Chaddr != o.Chaddr {
+		t.Fatal("transaction mismatch")
+	}
+	if p.MessageType() != DHCPRequest {
+		t.Fatalf("expect %d, got %d", DHCPRequest, p.MessageType())
+	}
+	if b, _ := p.Option(RequestedIPAddress); !net.IP(b).Equal(net.IPv4(10, 0, 0, 5)) {
+		t.Fatalf("expect 10.0.0.5, got %v", b)
+	}
+	if !p.ServerID().Equal(net.IPv4(10, 0, 0, 1)) {
+		t.Fatalf("expect 10.0.0.1, got %s", p.ServerID())
+	}
+
+	r := NewReleasePacket(o)
+	if r.MessageType() != DHCPRelease || r.Ciaddr != o.Yiaddr {
+		t.Fatal("invalid release packet")
+	}
+}
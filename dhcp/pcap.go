@@ -0,0 +1,446 @@
+package dhcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+// Link types (http://www.tcpdump.org/linktypes.html)
+const (
+	LinkTypeEthernet = 1
+	LinkTypeRaw      = 101
+	LinkTypeLinuxSLL = 113
+	LinkTypeIPv4     = 228
+)
+
+const (
+	pcapMagic     = 0xa1b2c3d4
+	pcapMagicNsec = 0xa1b23c4d
+
+	pcapngSectionHeader  = 0x0a0d0d0a
+	pcapngInterface      = 1
+	pcapngSimplePacket   = 3
+	pcapngEnhancedPacket = 6
+	pcapngByteOrderMagic = 0x1a2b3c4d
+	pcapngOptionEnd      = 0
+	pcapngOptionComment  = 1
+	pcapngOptionTSResol  = 9
+	pcapngMaxBlockSize   = 16 * 1024 * 1024
+
+	sllHeaderSize = 16
+)
+
+var (
+	ErrCaptureFormat = errors.New("dhcp: unknown capture file format")
+	ErrCorruptedFile = errors.New("dhcp: corrupted capture file")
+	ErrLinkType      = errors.New("dhcp: unsupported link type")
+	ErrReadOnly      = errors.New("dhcp: capture is read only")
+)
+
+type captureInterface struct {
+	linkType int
+	tsUnit   time.Duration // timestamp resolution
+	tsScale  uint64        // units per second, if not whole nanoseconds
+}
+
+// CapturePeer is a Peer reading DHCP packets from a pcap or pcapng capture
+// file. Packets that aren't IPv4 UDP datagrams to or from DHCP ports are
+// skipped, and Receive returns io.EOF at the end of the capture.
+type CapturePeer struct {
+	r        *bufio.Reader
+	c        io.Closer
+	order    binary.ByteOrder
+	ng       bool
+	ifaces   []captureInterface
+	last     time.Time
+	lastMAC  net.HardwareAddr
+	comments []string
+}
+
+// OpenCapture opens the named pcap or pcapng file for reading.
+func OpenCapture(name string) (*CapturePeer, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	cp, err := NewCaptureReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	cp.c = f
+	return cp, nil
+}
+
+// NewCaptureReader returns a peer reading packets from a pcap or pcapng
+// stream.
+func NewCaptureReader(r io.Reader) (*CapturePeer, error) {
+	cp := &CapturePeer{r: bufio.NewReader(r)}
+
+	b, err := cp.r.Peek(4)
+	if err != nil {
+		return nil, ErrCaptureFormat
+	}
+
+	if binary.LittleEndian.Uint32(b) == pcapngSectionHeader {
+		cp.ng = true
+		// the section header sets the byte order
+		return cp, cp.readSectionHeader()
+	}
+
+	return cp, cp.readFileHeader()
+}
+
+func (cp *CapturePeer) readFileHeader() error {
+	h := make([]byte, 24)
+	if _, err := io.ReadFull(cp.r, h); err != nil {
+		return ErrCaptureFormat
+	}
+
+	unit := time.Microsecond
+	switch {
+	case binary.LittleEndian.Uint32(h) == pcapMagic:
+		cp.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(h) == pcapMagic:
+		cp.order = binary.BigEndian
+	case binary.LittleEndian.Uint32(h) == pcapMagicNsec:
+		cp.order = binary.LittleEndian
+		unit = time.Nanosecond
+	case binary.BigEndian.Uint32(h) == pcapMagicNsec:
+		cp.order = binary.BigEndian
+		unit = time.Nanosecond
+	default:
+		return ErrCaptureFormat
+	}
+
+	cp.ifaces = []captureInterface{{
+		linkType: int(cp.order.Uint32(h[20:]) & 0xffff),
+		tsUnit:   unit,
+	}}
+
+	return nil
+}
+
+// Close closes the capture file.
+func (cp *CapturePeer) Close() error {
+	if cp.c != nil {
+		return cp.c.Close()
+	}
+	return nil
+}
+
+func (cp *CapturePeer) Send(ctx context.Context, p *Packet) error {
+	return ErrReadOnly
+}
+
+func (cp *CapturePeer) Broadcast(ctx context.Context, p *Packet) error {
+	return ErrReadOnly
+}
+
+// Receive returns the next DHCP packet in the capture.
+func (cp *CapturePeer) Receive(ctx context.Context) (Packet, *net.UDPAddr, error) {
+	var p Packet
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return p, nil, err
+		}
+
+		var iface int
+		var ts uint64
+		var data []byte
+		var comments []string
+		var err error
+
+		if cp.ng {
+			iface, ts, data, comments, err = cp.readPacketBlock()
+		} else {
+			ts, data, err = cp.readRecord()
+		}
+		if err != nil {
+			return p, nil, err
+		}
+
+		if iface >= len(cp.ifaces) {
+			return p, nil, ErrCorruptedFile
+		}
+		ci := cp.ifaces[iface]
+
+		f, err := decodeLink(ci.linkType, data)
+		if err != nil || !f.IsDHCP() {
+			continue
+		}
+
+		cp.last = ci.time(ts)
+		cp.lastMAC = f.SrcMAC
+		cp.comments = comments
+		err = p.Decode(f.Payload)
+		return p, f.Source(), err
+	}
+}
+
+// Timestamp returns the capture time of the last packet received.
+func (cp *CapturePeer) Timestamp() time.Time {
+	return cp.last
+}
+
+// Comments returns the pcapng comments of the last packet received.
+func (cp *CapturePeer) Comments() []string {
+	return cp.comments
+}
+
+// RemoteMAC returns the hardware address of the sender of the last
+// packet received, or nil if the link type doesn't carry it.
+func (cp *CapturePeer) RemoteMAC() net.HardwareAddr {
+	return cp.lastMAC
+}
+
+func (ci captureInterface) time(ts uint64) time.Time {
+	if ts == 0 {
+		// simple packet blocks have no timestamp
+		return time.Time{}
+	}
+	if ci.tsScale > 0 {
+		// resolution finer than a nanosecond
+		sec := ts / ci.tsScale
+		frac := ts % ci.tsScale
+		return time.Unix(int64(sec), int64(frac*uint64(time.Second)/ci.tsScale))
+	}
+	perSec := uint64(time.Second / ci.tsUnit)
+	sec := ts / perSec
+	frac := ts % perSec
+	return time.Unix(int64(sec), int64(frac)*int64(ci.tsUnit))
+}
+
+// readRecord reads a pcap packet record, returning the timestamp in units
+// of the file resolution.
+func (cp *CapturePeer) readRecord() (uint64, []byte, error) {
+	h := make([]byte, 16)
+	if _, err := io.ReadFull(cp.r, h); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = ErrCorruptedFile
+		}
+		return 0, nil, err
+	}
+
+	sec := uint64(cp.order.Uint32(h[0:]))
+	frac := uint64(cp.order.Uint32(h[4:]))
+	size := cp.order.Uint32(h[8:])
+	if size > pcapngMaxBlockSize {
+		return 0, nil, ErrCorruptedFile
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(cp.r, data); err != nil {
+		return 0, nil, ErrCorruptedFile
+	}
+
+	perSec := uint64(time.Second / cp.ifaces[0].tsUnit)
+	return sec*perSec + frac, data, nil
+}
+
+// readPacketBlock reads pcapng blocks until a packet block is found.
+func (cp *CapturePeer) readPacketBlock() (int, uint64, []byte, []string, error) {
+	for {
+		typ, body, err := cp.readBlock()
+		if err != nil {
+			return 0, 0, nil, nil, err
+		}
+
+		switch typ {
+		case pcapngSectionHeader:
+			err = cp.sectionHeader(body)
+		case pcapngInterface:
+			err = cp.interfaceBlock(body)
+		case pcapngEnhancedPacket:
+			if len(body) < 20 {
+				return 0, 0, nil, nil, ErrCorruptedFile
+			}
+			iface := int(cp.order.Uint32(body[0:]))
+			ts := uint64(cp.order.Uint32(body[4:]))<<32 |
+				uint64(cp.order.Uint32(body[8:]))
+			size := int(cp.order.Uint32(body[12:]))
+			if size > len(body)-20 {
+				return 0, 0, nil, nil, ErrCorruptedFile
+			}
+			var comments []string
+			if n := 20 + (size+3)&^3; n <= len(body) {
+				cp.options(body[n:], func(code uint16, v []byte) {
+					if code == pcapngOptionComment {
+						comments = append(comments, string(v))
+					}
+				})
+			}
+			return iface, ts, body[20 : 20+size], comments, nil
+		case pcapngSimplePacket:
+			// no timestamp, captured on the first interface
+			if len(body) < 4 {
+				return 0, 0, nil, nil, ErrCorruptedFile
+			}
+			size := int(cp.order.Uint32(body[0:]))
+			if size > len(body)-4 {
+				size = len(body) - 4
+			}
+			return 0, 0, body[4 : 4+size], nil, nil
+		}
+		if err != nil {
+			return 0, 0, nil, nil, err
+		}
+	}
+}
+
+// options calls fn for each option in a block.
+func (cp *CapturePeer) options(b []byte, fn func(code uint16, value []byte)) {
+	for len(b) >= 4 {
+		code := cp.order.Uint16(b[0:])
+		size := int(cp.order.Uint16(b[2:]))
+		if code == pcapngOptionEnd || 4+size > len(b) {
+			return
+		}
+		fn(code, b[4:4+size])
+		if 4+(size+3)&^3 > len(b) {
+			return
+		}
+		b = b[4+(size+3)&^3:]
+	}
+}
+
+// readSectionHeader reads the first section header block.
+func (cp *CapturePeer) readSectionHeader() error {
+	typ, body, err := cp.readBlock()
+	if err != nil || typ != pcapngSectionHeader {
+		return ErrCaptureFormat
+	}
+	return cp.sectionHeader(body)
+}
+
+// readBlock reads a pcapng block, returning its type and body.
+func (cp *CapturePeer) readBlock() (uint32, []byte, error) {
+	h := make([]byte, 12)
+	if _, err := io.ReadFull(cp.r, h[:8]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = ErrCorruptedFile
+		}
+		return 0, nil, err
+	}
+
+	typ := binary.LittleEndian.Uint32(h)
+	if typ == pcapngSectionHeader {
+		// the byte order may change in a new section
+		if _, err := io.ReadFull(cp.r, h[8:12]); err != nil {
+			return 0, nil, ErrCorruptedFile
+		}
+		switch {
+		case binary.LittleEndian.Uint32(h[8:]) == pcapngByteOrderMagic:
+			cp.order = binary.LittleEndian
+		case binary.BigEndian.Uint32(h[8:]) == pcapngByteOrderMagic:
+			cp.order = binary.BigEndian
+		default:
+			return 0, nil, ErrCorruptedFile
+		}
+	} else if cp.order == nil {
+		return 0, nil, ErrCorruptedFile
+	}
+
+	typ = cp.order.Uint32(h)
+	size := cp.order.Uint32(h[4:])
+	if size < 12 || size%4 != 0 || size > pcapngMaxBlockSize {
+		return 0, nil, ErrCorruptedFile
+	}
+
+	b := make([]byte, size-8)
+	n := 0
+	if typ == pcapngSectionHeader {
+		copy(b, h[8:12])
+		n = 4
+	}
+	if _, err := io.ReadFull(cp.r, b[n:]); err != nil {
+		return 0, nil, ErrCorruptedFile
+	}
+
+	// drop the trailing block length
+	return typ, b[:len(b)-4], nil
+}
+
+func (cp *CapturePeer) sectionHeader(body []byte) error {
+	if len(body) < 16 {
+		return ErrCorruptedFile
+	}
+	// interface ids are local to a section
+	cp.ifaces = nil
+	return nil
+}
+
+func (cp *CapturePeer) interfaceBlock(body []byte) error {
+	if len(body) < 8 {
+		return ErrCorruptedFile
+	}
+
+	ci := captureInterface{
+		linkType: int(cp.order.Uint16(body[0:])),
+		tsUnit:   time.Microsecond,
+	}
+
+	cp.options(body[8:], func(code uint16, v []byte) {
+		if code == pcapngOptionTSResol && len(v) >= 1 {
+			ci.setResolution(v[0])
+		}
+	})
+
+	cp.ifaces = append(cp.ifaces, ci)
+	return nil
+}
+
+// setResolution sets the timestamp resolution from the if_tsresol option:
+// a negative power of 10, or of 2 if the most significant bit is set.
+func (ci *captureInterface) setResolution(r byte) {
+	var perSec uint64 = 1
+	exp := r & 0x7f
+	for i := byte(0); i < exp && perSec < 1<<62; i++ {
+		if r&0x80 != 0 {
+			perSec *= 2
+		} else {
+			perSec *= 10
+		}
+	}
+
+	if perSec > uint64(time.Second) {
+		ci.tsScale = perSec
+		return
+	}
+	ci.tsUnit = time.Second / time.Duration(perSec)
+	if time.Duration(perSec)*ci.tsUnit != time.Second {
+		// not an exact number of nanoseconds
+		ci.tsScale = perSec
+	}
+}
+
+// decodeLink parses a frame of the given link type containing an IPv4 UDP
+// datagram.
+func decodeLink(linkType int, b []byte) (*Frame, error) {
+	switch linkType {
+	case LinkTypeEthernet:
+		return DecodeFrame(b)
+	case LinkTypeRaw, LinkTypeIPv4:
+		f := &Frame{}
+		return f, decodeIPv4(b, f)
+	case LinkTypeLinuxSLL:
+		// packet type, ARPHRD type, address length, address, protocol
+		if len(b) < sllHeaderSize ||
+			binary.BigEndian.Uint16(b[14:]) != etherTypeIPv4 {
+			return nil, ErrNotUDP
+		}
+		f := &Frame{}
+		if binary.BigEndian.Uint16(b[4:]) == 6 {
+			f.SrcMAC = net.HardwareAddr(append([]byte{}, b[6:12]...))
+		}
+		return f, decodeIPv4(b[sllHeaderSize:], f)
+	}
+	return nil, ErrLinkType
+}
@@ -0,0 +1,167 @@
+package dhcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func testFrame(t *testing.T) []byte {
+	p := NewDiscoverPacket()
+	p.SetClientMAC("01:02:03:04:05:06")
+	data, err := p.Encode(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return EncodeFrame(&Frame{
+		SrcMAC:  net.HardwareAddr{1, 2, 3, 4, 5, 6},
+		DstMAC:  net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		SrcIP:   net.IPv4zero,
+		DstIP:   net.IPv4bcast,
+		SrcPort: ClientPort,
+		DstPort: ServerPort,
+		Payload: data,
+	})
+}
+
+func checkCapture(t *testing.T, cp *CapturePeer, ts time.Time) {
+	p, remote, err := cp.Receive(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.MessageType() != DHCPDiscover {
+		t.Fatalf("expect discover, got message type %d", p.MessageType())
+	}
+	if remote.Port != ClientPort {
+		t.Fatalf("expect port %d, got %d", ClientPort, remote.Port)
+	}
+	if cp.RemoteMAC().String() != "01:02:03:04:05:06" {
+		t.Fatalf("unexpected remote MAC %s", cp.RemoteMAC())
+	}
+	if !cp.Timestamp().Equal(ts) {
+		t.Fatalf("expect timestamp %s, got %s", ts, cp.Timestamp())
+	}
+	if _, _, err := cp.Receive(context.Background()); err != io.EOF {
+		t.Fatalf("expect %v, got %v", io.EOF, err)
+	}
+}
+
+func TestCapturePcap(t *testing.T) {
+	frame := testFrame(t)
+
+	var b bytes.Buffer
+	le := binary.LittleEndian
+	binary.Write(&b, le, []uint32{pcapMagic, 0x00040002, 0, 0, 65535, LinkTypeEthernet})
+
+	// non-DHCP frame is skipped
+	other := make([]byte, 60)
+	binary.Write(&b, le, []uint32{1000, 0, uint32(len(other)), uint32(len(other))})
+	b.Write(other)
+
+	binary.Write(&b, le, []uint32{1000, 250000, uint32(len(frame)), uint32(len(frame))})
+	b.Write(frame)
+
+	cp, err := NewCaptureReader(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCapture(t, cp, time.Unix(1000, 250000000))
+}
+
+func TestCapturePcapng(t *testing.T) {
+	frame := testFrame(t)
+
+	var b bytes.Buffer
+	be := binary.BigEndian
+	block := func(typ uint32, body []byte) {
+		for len(body)%4 != 0 {
+			body = append(body, 0)
+		}
+		binary.Write(&b, be, []uint32{typ, uint32(len(body) + 12)})
+		b.Write(body)
+		binary.Write(&b, be, uint32(len(body)+12))
+	}
+
+	// section header
+	block(pcapngSectionHeader, []byte{
+		0x1a, 0x2b, 0x3c, 0x4d, 0, 1, 0, 0,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+	})
+
+	// interface with nanosecond resolution
+	block(pcapngInterface, []byte{
+		0, LinkTypeEthernet, 0, 0, 0, 0, 0xff, 0xff,
+		0, pcapngOptionTSResol, 0, 1, 9, 0, 0, 0,
+		0, 0, 0, 0,
+	})
+
+	ts := uint64(1000*time.Second + 5)
+	epb := make([]byte, 20)
+	be.PutUint32(epb[4:], uint32(ts>>32))
+	be.PutUint32(epb[8:], uint32(ts))
+	be.PutUint32(epb[12:], uint32(len(frame)))
+	be.PutUint32(epb[16:], uint32(len(frame)))
+	block(pcapngEnhancedPacket, append(epb, frame...))
+
+	cp, err := NewCaptureReader(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCapture(t, cp, time.Unix(1000, 5))
+}
+
+func TestCaptureInvalid(t *testing.T) {
+	if _, err := NewCaptureReader(bytes.NewReader([]byte("not a capture file"))); err != ErrCaptureFormat {
+		t.Fatalf("expect %v, got %v", ErrCaptureFormat, err)
+	}
+}
+
+func TestDecodeLinuxSLL(t *testing.T) {
+	frame := testFrame(t)
+
+	sll := []byte{0, 4, 0, 1, 0, 6, 1, 2, 3, 4, 5, 6, 0, 0, 0x08, 0x00}
+	f, err := decodeLink(LinkTypeLinuxSLL, append(sll, frame[etherHeaderSize:]...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.SrcMAC.String() != "01:02:03:04:05:06" || !f.IsDHCP() {
+		t.Fatalf("unexpected frame %v", f)
+	}
+}
+
+func TestCaptureWriter(t *testing.T) {
+	var b bytes.Buffer
+
+	cw, err := NewCaptureWriter(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := DecodeFrame(testFrame(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Unix(1000, 123456789)
+	if err := cw.WriteFrame(ts, f, "rogue server", "x"); err != nil {
+		t.Fatal(err)
+	}
+
+	cp, err := NewCaptureReader(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := cp.Receive(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	c := cp.Comments()
+	if len(c) != 2 || c[0] != "rogue server" || c[1] != "x" {
+		t.Fatalf("unexpected comments %q", c)
+	}
+	if !cp.Timestamp().Equal(ts) {
+		t.Fatalf("expect timestamp %s, got %s", ts, cp.Timestamp())
+	}
+}
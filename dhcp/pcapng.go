@@ -0,0 +1,114 @@
+package dhcp
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+	"time"
+)
+
+const pcapngOptionUserAppl = 4
+
+// CaptureWriter writes Ethernet frames to a pcapng file with nanosecond
+// timestamps and optional per-packet comments.
+type CaptureWriter struct {
+	w io.Writer
+	c io.Closer
+}
+
+// CreateCapture creates the named pcapng file.
+func CreateCapture(name string) (*CaptureWriter, error) {
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	cw, err := NewCaptureWriter(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	cw.c = f
+	return cw, nil
+}
+
+// NewCaptureWriter writes the pcapng section and interface headers to w
+// and returns a writer for the packets that follow.
+func NewCaptureWriter(w io.Writer) (*CaptureWriter, error) {
+	cw := &CaptureWriter{w: w}
+
+	shb := make([]byte, 16)
+	binary.LittleEndian.PutUint32(shb[0:], pcapngByteOrderMagic)
+	binary.LittleEndian.PutUint16(shb[4:], 1) // version 1.0
+	binary.LittleEndian.PutUint64(shb[8:], ^uint64(0))
+	shb = appendOption(shb, pcapngOptionUserAppl, []byte("dhcpcheck"))
+	shb = appendOption(shb, pcapngOptionEnd, nil)
+	if err := cw.writeBlock(pcapngSectionHeader, shb); err != nil {
+		return nil, err
+	}
+
+	idb := make([]byte, 8)
+	binary.LittleEndian.PutUint16(idb[0:], LinkTypeEthernet)
+	idb = appendOption(idb, pcapngOptionTSResol, []byte{9})
+	idb = appendOption(idb, pcapngOptionEnd, nil)
+	if err := cw.writeBlock(pcapngInterface, idb); err != nil {
+		return nil, err
+	}
+
+	return cw, nil
+}
+
+// WriteFrame writes the frame captured at time ts, with a comment option
+// for each of the comments given.
+func (cw *CaptureWriter) WriteFrame(ts time.Time, f *Frame, comments ...string) error {
+	data := EncodeFrame(f)
+
+	b := make([]byte, 20, 20+len(data)+8)
+	n := uint64(ts.UnixNano())
+	binary.LittleEndian.PutUint32(b[4:], uint32(n>>32))
+	binary.LittleEndian.PutUint32(b[8:], uint32(n))
+	binary.LittleEndian.PutUint32(b[12:], uint32(len(data)))
+	binary.LittleEndian.PutUint32(b[16:], uint32(len(data)))
+	b = append(b, pad(data)...)
+
+	if len(comments) > 0 {
+		for _, c := range comments {
+			b = appendOption(b, pcapngOptionComment, []byte(c))
+		}
+		b = appendOption(b, pcapngOptionEnd, nil)
+	}
+
+	return cw.writeBlock(pcapngEnhancedPacket, b)
+}
+
+// Close closes the capture file.
+func (cw *CaptureWriter) Close() error {
+	if cw.c != nil {
+		return cw.c.Close()
+	}
+	return nil
+}
+
+func (cw *CaptureWriter) writeBlock(typ uint32, body []byte) error {
+	size := uint32(len(body) + 12)
+	b := make([]byte, 0, size)
+	b = binary.LittleEndian.AppendUint32(b, typ)
+	b = binary.LittleEndian.AppendUint32(b, size)
+	b = append(b, body...)
+	b = binary.LittleEndian.AppendUint32(b, size)
+	_, err := cw.w.Write(b)
+	return err
+}
+
+func appendOption(b []byte, code uint16, value []byte) []byte {
+	b = binary.LittleEndian.AppendUint16(b, code)
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(value)))
+	return append(b, pad(value)...)
+}
+
+// pad returns b padded with zeros to a multiple of 4 bytes.
+func pad(b []byte) []byte {
+	if len(b)%4 == 0 {
+		return b
+	}
+	return append(b[:len(b):len(b)], make([]byte, 4-len(b)%4)...)
+}
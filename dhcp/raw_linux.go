@@ -0,0 +1,227 @@
+package dhcp
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+)
+
+// pollInterval is how often a blocked Receive checks its context.
+const pollInterval = 200 * time.Millisecond
+
+// RawPeer sends and receives DHCP packets through a packet socket bound
+// to a network interface. Ethernet, IP and UDP headers are built by the
+// peer, so it doesn't need the DHCP ports to be available, and can capture
+// packets addressed to other hosts on the segment.
+type RawPeer struct {
+	fd         int
+	iface      *net.Interface
+	localPort  int // zero to receive packets to any DHCP port
+	remotePort int
+	remoteIP   net.IP
+	remoteMAC  net.HardwareAddr
+	lastMAC    net.HardwareAddr
+}
+
+func htons(x uint16) uint16 {
+	return x<<8 | x>>8
+}
+
+func newRawPeer(name string, localPort, remotePort int, promisc bool) (*RawPeer, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	proto := htons(syscall.ETH_P_IP)
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, int(proto))
+	if err != nil {
+		return nil, fmt.Errorf("dhcp: packet socket: %s", err)
+	}
+
+	err = syscall.Bind(fd, &syscall.SockaddrLinklayer{
+		Protocol: proto,
+		Ifindex:  iface.Index,
+	})
+	if err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("dhcp: bind to %s: %s", name, err)
+	}
+
+	if promisc {
+		// struct packet_mreq
+		mreq := make([]byte, 16)
+		binary.NativeEndian.PutUint32(mreq[0:], uint32(iface.Index))
+		binary.NativeEndian.PutUint16(mreq[4:], syscall.PACKET_MR_PROMISC)
+		err = syscall.SetsockoptString(fd, syscall.SOL_PACKET,
+			syscall.PACKET_ADD_MEMBERSHIP, string(mreq))
+		if err != nil {
+			syscall.Close(fd)
+			return nil, fmt.Errorf("dhcp: promiscuous mode: %s", err)
+		}
+	}
+
+	return &RawPeer{
+		fd:         fd,
+		iface:      iface,
+		localPort:  localPort,
+		remotePort: remotePort,
+	}, nil
+}
+
+// NewRawClient returns a peer sending packets to the server port and
+// receiving packets addressed to the client port on the named interface.
+func NewRawClient(iface string) (*RawPeer, error) {
+	return newRawPeer(iface, ClientPort, ServerPort, false)
+}
+
+// NewRawServer returns a peer sending packets to the client port and
+// receiving packets addressed to the server port on the named interface.
+func NewRawServer(iface string) (*RawPeer, error) {
+	return newRawPeer(iface, ServerPort, ClientPort, false)
+}
+
+// NewRawSniffer returns a peer receiving all DHCP packets seen on the
+// named interface, which is put in promiscuous mode.
+func NewRawSniffer(iface string) (*RawPeer, error) {
+	return newRawPeer(iface, 0, ServerPort, true)
+}
+
+// SetRemote sets the address of the peer packets are sent to. If mac is
+// nil, packets are sent to the Ethernet broadcast address.
+func (r *RawPeer) SetRemote(ip net.IP, mac net.HardwareAddr) {
+	r.remoteIP = ip
+	r.remoteMAC = mac
+}
+
+func (r *RawPeer) Close() error {
+	return syscall.Close(r.fd)
+}
+
+func (r *RawPeer) send(ctx context.Context, p *Packet, ip net.IP, mac net.HardwareAddr) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	data, err := p.Encode(0)
+	if err != nil {
+		return err
+	}
+
+	if mac == nil {
+		mac = net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	}
+
+	src := net.IPv4zero
+	if addrs, err := r.iface.Addrs(); err == nil {
+		for _, a := range addrs {
+			if n, ok := a.(*net.IPNet); ok && n.IP.To4() != nil {
+				src = n.IP
+				break
+			}
+		}
+	}
+
+	// clients without an address send from 0.0.0.0
+	if r.localPort == ClientPort && p.Ciaddr == (IPv4Address{}) {
+		src = net.IPv4zero
+	}
+
+	localPort := r.localPort
+	if localPort == 0 {
+		localPort = ClientPort
+	}
+
+	frame := EncodeFrame(&Frame{
+		SrcMAC:  r.iface.HardwareAddr,
+		DstMAC:  mac,
+		SrcIP:   src,
+		DstIP:   ip,
+		SrcPort: localPort,
+		DstPort: r.remotePort,
+		Payload: data,
+	})
+
+	return syscall.Sendto(r.fd, frame, 0, &syscall.SockaddrLinklayer{
+		Ifindex: r.iface.Index,
+		Halen:   6,
+		Addr:    [8]byte{mac[0], mac[1], mac[2], mac[3], mac[4], mac[5]},
+	})
+}
+
+func (r *RawPeer) Send(ctx context.Context, p *Packet) error {
+	if r.remoteIP == nil {
+		return fmt.Errorf("dhcp: remote address not set")
+	}
+	return r.send(ctx, p, r.remoteIP, r.remoteMAC)
+}
+
+func (r *RawPeer) Broadcast(ctx context.Context, p *Packet) error {
+	return r.send(ctx, p, net.IPv4bcast, nil)
+}
+
+func (r *RawPeer) Receive(ctx context.Context) (Packet, *net.UDPAddr, error) {
+	var p Packet
+
+	b := make([]byte, maxPacketSize+etherHeaderSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return p, nil, err
+		}
+
+		// wake up periodically to check the context
+		wait := pollInterval
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			wait = time.Until(deadline)
+			if wait <= 0 {
+				return p, nil, context.DeadlineExceeded
+			}
+		}
+		tv := syscall.NsecToTimeval(wait.Nanoseconds())
+		err := syscall.SetsockoptTimeval(r.fd, syscall.SOL_SOCKET,
+			syscall.SO_RCVTIMEO, &tv)
+		if err != nil {
+			return p, nil, err
+		}
+
+		n, from, err := syscall.Recvfrom(r.fd, b, 0)
+		switch err {
+		case nil:
+		case syscall.EAGAIN, syscall.EINTR:
+			continue
+		default:
+			return p, nil, err
+		}
+
+		// skip our own packets
+		if ll, ok := from.(*syscall.SockaddrLinklayer); ok &&
+			ll.Pkttype == syscall.PACKET_OUTGOING {
+			continue
+		}
+
+		f, err := DecodeFrame(b[:n])
+		if err != nil || !r.accept(f) {
+			continue
+		}
+
+		r.lastMAC = f.SrcMAC
+		err = p.Decode(f.Payload)
+		return p, f.Source(), err
+	}
+}
+
+func (r *RawPeer) accept(f *Frame) bool {
+	if r.localPort == 0 {
+		return f.IsDHCP()
+	}
+	return f.DstPort == r.localPort && f.SrcPort == r.remotePort
+}
+
+// RemoteMAC returns the hardware address of the sender of the last
+// packet received.
+func (r *RawPeer) RemoteMAC() net.HardwareAddr {
+	return r.lastMAC
+}
@@ -0,0 +1,48 @@
+package dhcp
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// Raw sockets need CAP_NET_RAW. Frames sent on the loopback interface are
+// received by other packet sockets bound to it, which can also be used to
+// test with both ends of a veth pair.
+func TestRawLoopback(t *testing.T) {
+	server, err := NewRawServer("lo")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer server.Close()
+
+	client, err := NewRawClient("lo")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	p := NewDiscoverPacket()
+	p.SetClientMAC("01:02:03:04:05:06")
+	if err := client.Broadcast(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+
+	q, remote, err := server.Receive(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Xid != p.Xid || remote.Port != ClientPort {
+		t.Fatalf("unexpected packet from %s", remote)
+	}
+
+	// nothing is addressed to the client port
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, _, err := client.Receive(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expect %v, got %v", context.DeadlineExceeded, err)
+	}
+}
@@ -0,0 +1,49 @@
+//go:build !linux
+// +build !linux
+
+package dhcp
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+var errRawUnsupported = errors.New("dhcp: raw sockets not supported on this platform")
+
+// RawPeer is only available on Linux.
+type RawPeer struct{}
+
+func NewRawClient(iface string) (*RawPeer, error) {
+	return nil, errRawUnsupported
+}
+
+func NewRawServer(iface string) (*RawPeer, error) {
+	return nil, errRawUnsupported
+}
+
+func NewRawSniffer(iface string) (*RawPeer, error) {
+	return nil, errRawUnsupported
+}
+
+func (r *RawPeer) SetRemote(ip net.IP, mac net.HardwareAddr) {}
+
+func (r *RawPeer) Close() error {
+	return errRawUnsupported
+}
+
+func (r *RawPeer) Send(ctx context.Context, p *Packet) error {
+	return errRawUnsupported
+}
+
+func (r *RawPeer) Broadcast(ctx context.Context, p *Packet) error {
+	return errRawUnsupported
+}
+
+func (r *RawPeer) Receive(ctx context.Context) (Packet, *net.UDPAddr, error) {
+	return Packet{}, nil, errRawUnsupported
+}
+
+func (r *RawPeer) RemoteMAC() net.HardwareAddr {
+	return nil
+}
@@ -0,0 +1,508 @@
+package dhcp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Kind describes how the data of an option is encoded.
+type Kind int
+
+const (
+	KindBytes           Kind = iota // opaque data, decoded as []byte
+	KindNone                        // no data (Pad and End options)
+	KindIP                          // IPv4 address, decoded as net.IP
+	KindIPList                      // list of IPv4 addresses, as []net.IP
+	KindIPPairs                     // list of address pairs, as [][2]net.IP
+	KindUint8                       // 8-bit integer, as uint8
+	KindUint16                      // 16-bit integer, as uint16
+	KindUint16List                  // list of 16-bit integers, as []uint16
+	KindUint32                      // 32-bit integer, as uint32
+	KindInt32                       // signed 32-bit integer, as int32
+	KindDuration                    // 32-bit seconds, as time.Duration
+	KindBool                        // boolean, as bool
+	KindString                      // string, as string
+	KindDomainList                  // RFC 1035 domain names, as []string
+	KindOptionList                  // list of option codes, as []byte
+	KindMessageType                 // DHCP message type, as byte
+	KindClientID                    // hardware type and address, as []byte
+	KindClientFQDN                  // RFC 4702 client FQDN, as FQDN
+	KindRelayAgentInfo              // RFC 3046 sub-options, as *RelayAgentInfo
+	KindStaticRoutes                // classful routes, as []Route
+	KindClasslessRoutes             // RFC 3442 routes, as []Route
+)
+
+var kindNames = map[Kind]string{
+	KindBytes:           "bytes",
+	KindNone:            "none",
+	KindIP:              "IP address",
+	KindIPList:          "IP address list",
+	KindIPPairs:         "IP address pairs",
+	KindUint8:           "uint8",
+	KindUint16:          "uint16",
+	KindUint16List:      "uint16 list",
+	KindUint32:          "uint32",
+	KindInt32:           "int32",
+	KindDuration:        "duration",
+	KindBool:            "boolean",
+	KindString:          "string",
+	KindDomainList:      "domain list",
+	KindOptionList:      "option list",
+	KindMessageType:     "message type",
+	KindClientID:        "client identifier",
+	KindClientFQDN:      "client FQDN",
+	KindRelayAgentInfo:  "relay agent information",
+	KindStaticRoutes:    "static routes",
+	KindClasslessRoutes: "classless static routes",
+}
+
+func (k Kind) String() string {
+	if s, ok := kindNames[k]; ok {
+		return s
+	}
+	return fmt.Sprintf("kind %d", int(k))
+}
+
+var ErrInvalidOption = errors.New("dhcp: invalid option data")
+
+// OptionInfo describes a known DHCP option.
+type OptionInfo struct {
+	Code byte
+	Name string
+	RFC  string
+	Kind Kind
+}
+
+// Registry maps option codes to their descriptions.
+var Registry = map[byte]OptionInfo{}
+
+func register(code byte, name, rfc string, kind Kind) {
+	Registry[code] = OptionInfo{code, name, rfc, kind}
+}
+
+func init() {
+	// RFC 1497 Vendor Extensions
+	register(PadOption, "Pad Option", "RFC 2132", KindNone)
+	register(EndOption, "End Option", "RFC 2132", KindNone)
+	register(SubnetMask, "Subnet Mask", "RFC 2132", KindIP)
+	register(TimeOffset, "Time Offset", "RFC 2132", KindInt32)
+	register(Router, "Router", "RFC 2132", KindIPList)
+	register(TimeServer, "Time Server", "RFC 2132", KindIPList)
+	register(NameServer, "Name Server", "RFC 2132", KindIPList)
+	register(DomainNameServer, "Domain Name Server", "RFC 2132", KindIPList)
+	register(LogServer, "Log Server", "RFC 2132", KindIPList)
+	register(CookieServer, "Cookie Server", "RFC 2132", KindIPList)
+	register(LPRServer, "LPR Server", "RFC 2132", KindIPList)
+	register(ImpressServer, "Impress Server", "RFC 2132", KindIPList)
+	register(ResourceLocationServer, "Resource Location Server", "RFC 2132", KindIPList)
+	register(HostName, "Host Name", "RFC 2132", KindString)
+	register(BootFileSize, "Boot File Size", "RFC 2132", KindUint16)
+	register(MeritDumpFile, "Merit Dump File", "RFC 2132", KindString)
+	register(DomainName, "Domain Name", "RFC 2132", KindString)
+	register(SwapServer, "Swap Server", "RFC 2132", KindIP)
+	register(RootPath, "Root Path", "RFC 2132", KindString)
+	register(ExtensionsPath, "Extensions Path", "RFC 2132", KindString)
+
+	// IP Layer Parameters per Host
+	register(IPForwarding, "IP Forwarding", "RFC 2132", KindBool)
+	register(NonLocalSourceRouting, "Non-Local Source Routing", "RFC 2132", KindBool)
+	register(PolicyFilter, "Policy Filter", "RFC 2132", KindIPPairs)
+	register(MaximumDatagramReassemblySize, "Max Datagram Reassembly", "RFC 2132", KindUint16)
+	register(DefaultIPTimeToLive, "Default IP Time-to-live", "RFC 2132", KindUint8)
+	register(PathMTUAgingTimeout, "Path MTU Aging Timeout", "RFC 2132", KindDuration)
+	register(PathMTUPlateauTable, "Path MTU Plateau Table", "RFC 2132", KindUint16List)
+
+	// IP Layer Parameters per Interface
+	register(InterfaceMTU, "Interface MTU", "RFC 2132", KindUint16)
+	register(AllSubnetsAreLocal, "All Subnets Are Local", "RFC 2132", KindBool)
+	register(BroadcastAddress, "Broadcast Address", "RFC 2132", KindIP)
+	register(PerformMaskDiscovery, "Perform Mask Discovery", "RFC 2132", KindBool)
+	register(MaskSupplier, "Mask Supplier", "RFC 2132", KindBool)
+	register(PerformRouterDiscovery, "Perform Router Discovery", "RFC 2132", KindBool)
+	register(RouterSolicitationAddress, "Router Solicitation Address", "RFC 2132", KindIP)
+	register(StaticRoute, "Static Route", "RFC 2132", KindStaticRoutes)
+
+	// Link Layer Parameters per Interface
+	register(TrailerEncapsulation, "Trailer Encapsulation", "RFC 2132", KindBool)
+	register(ARPCacheTimeout, "ARP Cache Timeout", "RFC 2132", KindDuration)
+	register(EthernetEncapsulation, "Ethernet Encapsulation", "RFC 2132", KindBool)
+
+	// TCP Parameters
+	register(TCPDefaultTTL, "TCP Default TTL", "RFC 2132", KindUint8)
+	register(TCPKeepaliveInterval, "TCP Keepalive Interval", "RFC 2132", KindDuration)
+	register(TCPKeepaliveGarbage, "TCP Keepalive Garbage", "RFC 2132", KindBool)
+
+	// Application and Service Parameters
+	register(NISDomain, "NIS Domain", "RFC 2132", KindString)
+	register(NISServers, "NIS Servers", "RFC 2132", KindIPList)
+	register(NTPServers, "NTP Servers", "RFC 2132", KindIPList)
+	register(VendorSpecific, "Vendor Specific", "RFC 2132", KindBytes)
+	register(NetBIOSNameServer, "NetBIOS Name Server", "RFC 2132", KindIPList)
+	register(NetBIOSDatagramServer, "NetBIOS Datagram Server", "RFC 2132", KindIPList)
+	register(NetBIOSNodeType, "NetBIOS Node Type", "RFC 2132", KindUint8)
+	register(NetBIOSScope, "NetBIOS Scope", "RFC 2132", KindString)
+	register(XFontServer, "X Font Server", "RFC 2132", KindIPList)
+	register(XDisplayManager, "X Display Manager", "RFC 2132", KindIPList)
+	register(NISPlusDomain, "NIS+ Domain", "RFC 2132", KindString)
+	register(NISPlusServers, "NIS+ Servers", "RFC 2132", KindIPList)
+	register(MobileIPHomeAgent, "Mobile IP Home Agent", "RFC 2132", KindIPList)
+	register(SMTPServer, "SMTP Server", "RFC 2132", KindIPList)
+	register(POP3Server, "POP3 Server", "RFC 2132", KindIPList)
+	register(NNTPServer, "NNTP Server", "RFC 2132", KindIPList)
+	register(DefaultWWWServer, "Default WWW Server", "RFC 2132", KindIPList)
+	register(DefaultFingerServer, "Default Finger Server", "RFC 2132", KindIPList)
+	register(DefaultIRCServer, "Default IRC Server", "RFC 2132", KindIPList)
+	register(StreetTalkServer, "StreetTalk Server", "RFC 2132", KindIPList)
+	register(STDAServer, "STDA Server", "RFC 2132", KindIPList)
+
+	// DHCP Extensions
+	register(RequestedIPAddress, "Requested IP Address", "RFC 2132", KindIP)
+	register(IPAddressLeaseTime, "IP Address Lease Time", "RFC 2132", KindDuration)
+	register(OptionOverload, "Option Overload", "RFC 2132", KindUint8)
+	register(TFTPServerName, "TFTP Server Name", "RFC 2132", KindString)
+	register(BootfileName, "Bootfile Name", "RFC 2132", KindString)
+	register(DHCPMessageType, "DHCP Message Type", "RFC 2132", KindMessageType)
+	register(ServerIdentifier, "Server Identifier", "RFC 2132", KindIP)
+	register(ParameterRequestList, "Parameter Request List", "RFC 2132", KindOptionList)
+	register(Message, "Message", "RFC 2132", KindString)
+	register(MaxDHCPMessageSize, "Max DHCP Message Size", "RFC 2132", KindUint16)
+	register(RenewalTimeValue, "Renewal Time Value", "RFC 2132", KindDuration)
+	register(RebindingTimeValue, "Rebinding Time Value", "RFC 2132", KindDuration)
+	register(VendorClassIdentifier, "Vendor Class Identifier", "RFC 2132", KindString)
+	register(ClientIdentifier, "Client Identifier", "RFC 2132", KindClientID)
+
+	register(UserClass, "User Class", "RFC 3004", KindString)
+	register(ClientFQDN, "Client FQDN", "RFC 4702", KindClientFQDN)
+	register(RelayAgentInformation, "Relay Agent Information", "RFC 3046", KindRelayAgentInfo)
+	register(DomainSearch, "Domain Search", "RFC 3397", KindDomainList)
+	register(ClasslessStaticRoute, "Classless Static Route", "RFC 3442", KindClasslessRoutes)
+	register(MSClasslessStaticRoute, "MS Classless Static Route", "Microsoft", KindClasslessRoutes)
+	register(WebProxyServer, "Web Proxy Server", "draft-ietf-wrec-wpad-01", KindString)
+}
+
+// LookupOption returns the description of an option, and whether the
+// option is known.
+func LookupOption(code byte) (OptionInfo, bool) {
+	info, ok := Registry[code]
+	if !ok {
+		info = OptionInfo{Code: code, Kind: KindBytes}
+	}
+	return info, ok
+}
+
+// OptionName returns the name of an option, or its code if the option is
+// not known.
+func OptionName(code byte) string {
+	if info, ok := Registry[code]; ok {
+		return info.Name
+	}
+	return fmt.Sprintf("Option %d", code)
+}
+
+// Value decodes the option data according to its registered kind.
+func (o Option) Value() (interface{}, error) {
+	info, _ := LookupOption(o.Type)
+	return info.Kind.Decode(o.Data)
+}
+
+// SetValue encodes a value according to the registered kind of the option
+// and sets it in the packet.
+func (p *Packet) SetValue(code byte, v interface{}) error {
+	info, _ := LookupOption(code)
+	b, err := info.Kind.Encode(v)
+	if err != nil {
+		return err
+	}
+	return p.SetOption(code, b)
+}
+
+// FQDN is the value of the Client FQDN option (RFC 4702).
+type FQDN struct {
+	Flags  byte
+	RCode1 byte
+	RCode2 byte
+	Name   string
+}
+
+// Client FQDN flags
+const (
+	FQDNServerUpdate   = 0x01 // S
+	FQDNOverride       = 0x02 // O
+	FQDNEncoded        = 0x04 // E
+	FQDNNoServerUpdate = 0x08 // N
+)
+
+func ipList(b []byte) []net.IP {
+	var list []net.IP
+	for i := 0; i+4 <= len(b); i += 4 {
+		list = append(list, net.IPv4(b[i], b[i+1], b[i+2], b[i+3]))
+	}
+	return list
+}
+
+// Decode returns the value of option data of this kind.
+func (k Kind) Decode(b []byte) (interface{}, error) {
+	switch k {
+	case KindNone:
+		return nil, nil
+
+	case KindIP:
+		if len(b) != 4 {
+			return nil, ErrInvalidOption
+		}
+		return ipList(b)[0], nil
+
+	case KindIPList:
+		if len(b) == 0 || len(b)%4 != 0 {
+			return nil, ErrInvalidOption
+		}
+		return ipList(b), nil
+
+	case KindIPPairs:
+		if len(b) == 0 || len(b)%8 != 0 {
+			return nil, ErrInvalidOption
+		}
+		var pairs [][2]net.IP
+		list := ipList(b)
+		for i := 0; i < len(list); i += 2 {
+			pairs = append(pairs, [2]net.IP{list[i], list[i+1]})
+		}
+		return pairs, nil
+
+	case KindUint8, KindMessageType:
+		if len(b) != 1 {
+			return nil, ErrInvalidOption
+		}
+		return b[0], nil
+
+	case KindBool:
+		if len(b) != 1 {
+			return nil, ErrInvalidOption
+		}
+		return b[0] != 0, nil
+
+	case KindUint16:
+		if len(b) != 2 {
+			return nil, ErrInvalidOption
+		}
+		return binary.BigEndian.Uint16(b), nil
+
+	case KindUint16List:
+		if len(b) == 0 || len(b)%2 != 0 {
+			return nil, ErrInvalidOption
+		}
+		var list []uint16
+		for i := 0; i < len(b); i += 2 {
+			list = append(list, binary.BigEndian.Uint16(b[i:]))
+		}
+		return list, nil
+
+	case KindUint32, KindInt32, KindDuration:
+		if len(b) != 4 {
+			return nil, ErrInvalidOption
+		}
+		x := binary.BigEndian.Uint32(b)
+		switch k {
+		case KindInt32:
+			return int32(x), nil
+		case KindDuration:
+			return time.Duration(x) * time.Second, nil
+		}
+		return x, nil
+
+	case KindString:
+		return string(b), nil
+
+	case KindDomainList:
+		return decodeDomainList(b)
+
+	case KindOptionList:
+		return append([]byte{}, b...), nil
+
+	case KindClientID:
+		if len(b) < 2 {
+			return nil, ErrInvalidOption
+		}
+		return append([]byte{}, b...), nil
+
+	case KindClientFQDN:
+		if len(b) < 3 {
+			return nil, ErrInvalidOption
+		}
+		v := FQDN{Flags: b[0], RCode1: b[1], RCode2: b[2]}
+		if b[0]&FQDNEncoded == 0 {
+			v.Name = string(b[3:])
+			return v, nil
+		}
+		if len(b) > 3 {
+			name, _, err := decodeDomain(b[3:], 0)
+			if err != nil {
+				return nil, err
+			}
+			v.Name = name
+		}
+		return v, nil
+
+	case KindRelayAgentInfo:
+		return ParseRelayAgentInfo(b)
+
+	case KindStaticRoutes:
+		return ParseStaticRoutes(b)
+
+	case KindClasslessRoutes:
+		return ParseClasslessRoutes(b)
+	}
+
+	return append([]byte{}, b...), nil
+}
+
+// Encode returns the option data for a value of this kind. The value must
+// have the type returned by Decode.
+func (k Kind) Encode(v interface{}) ([]byte, error) {
+	var b []byte
+	var ok bool
+
+	switch k {
+	case KindNone:
+		ok = v == nil
+
+	case KindIP:
+		var ip net.IP
+		if ip, ok = v.(net.IP); ok {
+			if ip.To4() == nil {
+				return nil, ErrInvalidOption
+			}
+			b = append(b, ip.To4()...)
+		}
+
+	case KindIPList:
+		var list []net.IP
+		list, ok = v.([]net.IP)
+		for _, ip := range list {
+			if ip.To4() == nil {
+				return nil, fmt.Errorf("dhcp: %s: not an IPv4 address", ip)
+			}
+			b = append(b, ip.To4()...)
+		}
+
+	case KindIPPairs:
+		var list [][2]net.IP
+		list, ok = v.([][2]net.IP)
+		for _, pair := range list {
+			for _, ip := range pair {
+				if ip.To4() == nil {
+					return nil, fmt.Errorf("dhcp: %s: not an IPv4 address", ip)
+				}
+				b = append(b, ip.To4()...)
+			}
+		}
+
+	case KindUint8, KindMessageType:
+		var x byte
+		if x, ok = v.(byte); ok {
+			b = []byte{x}
+		}
+
+	case KindBool:
+		var x bool
+		if x, ok = v.(bool); ok {
+			b = []byte{0}
+			if x {
+				b[0] = 1
+			}
+		}
+
+	case KindUint16:
+		var x uint16
+		if x, ok = v.(uint16); ok {
+			b = make([]byte, 2)
+			binary.BigEndian.PutUint16(b, x)
+		}
+
+	case KindUint16List:
+		var list []uint16
+		list, ok = v.([]uint16)
+		for _, x := range list {
+			b = append(b, byte(x>>8), byte(x))
+		}
+
+	case KindUint32, KindInt32, KindDuration:
+		var x uint32
+		switch t := v.(type) {
+		case uint32:
+			x, ok = t, k == KindUint32
+		case int32:
+			x, ok = uint32(t), k == KindInt32
+		case time.Duration:
+			x, ok = uint32(t/time.Second), k == KindDuration
+		}
+		if ok {
+			b = make([]byte, 4)
+			binary.BigEndian.PutUint32(b, x)
+		}
+
+	case KindString:
+		var s string
+		if s, ok = v.(string); ok {
+			b = []byte(s)
+		}
+
+	case KindDomainList:
+		var list []string
+		if list, ok = v.([]string); ok {
+			return encodeDomainList(list)
+		}
+
+	case KindClientFQDN:
+		var f FQDN
+		if f, ok = v.(FQDN); ok {
+			b = []byte{f.Flags, f.RCode1, f.RCode2}
+			if f.Flags&FQDNEncoded == 0 {
+				b = append(b, f.Name...)
+			} else {
+				name, err := encodeDomainList([]string{f.Name})
+				if err != nil {
+					return nil, err
+				}
+				b = append(b, name...)
+			}
+		}
+
+	case KindRelayAgentInfo:
+		var r *RelayAgentInfo
+		if r, ok = v.(*RelayAgentInfo); ok {
+			return r.Encode()
+		}
+
+	case KindStaticRoutes:
+		var routes []Route
+		if routes, ok = v.([]Route); ok {
+			for _, r := range routes {
+				if r.Dest.IP.To4() == nil || r.Gateway.To4() == nil {
+					return nil, fmt.Errorf("dhcp: %s: not an IPv4 route", r)
+				}
+				b = append(b, r.Dest.IP.To4()...)
+				b = append(b, r.Gateway.To4()...)
+			}
+		}
+
+	case KindClasslessRoutes:
+		var routes []Route
+		if routes, ok = v.([]Route); ok {
+			return EncodeClasslessRoutes(routes)
+		}
+
+	default:
+		// KindBytes, KindOptionList, KindClientID
+		b, ok = v.([]byte)
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("dhcp: invalid %T value for %s option", v, k)
+	}
+
+	return b, nil
+}
@@ -0,0 +1,106 @@
+package dhcp
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRegistryNames(t *testing.T) {
+	if s := OptionName(Router); s != "Router" {
+		t.Fatalf("expect %q, got %q", "Router", s)
+	}
+	if s := OptionName(200); s != "Option 200" {
+		t.Fatalf("expect %q, got %q", "Option 200", s)
+	}
+}
+
+func TestKindRoundTrip(t *testing.T) {
+	values := []struct {
+		kind  Kind
+		value interface{}
+	}{
+		{KindIP, net.IPv4(192, 168, 0, 1)},
+		{KindIPList, []net.IP{net.IPv4(10, 0, 0, 1), net.IPv4(10, 0, 0, 2)}},
+		{KindIPPairs, [][2]net.IP{{net.IPv4(10, 0, 0, 0), net.IPv4(10, 0, 0, 1)}}},
+		{KindUint8, byte(64)},
+		{KindUint16, uint16(1500)},
+		{KindUint16List, []uint16{68, 296}},
+		{KindInt32, int32(-3600)},
+		{KindDuration, 12 * time.Hour},
+		{KindBool, true},
+		{KindString, "foo"},
+		{KindDomainList, []string{"example.com", "foo.example.com"}},
+		{KindClientFQDN, FQDN{FQDNEncoded | FQDNServerUpdate, 0, 0, "host.example.com"}},
+	}
+
+	for _, v := range values {
+		b, err := v.kind.Encode(v.value)
+		if err != nil {
+			t.Fatalf("%s: %s", v.kind, err)
+		}
+		x, err := v.kind.Decode(b)
+		if err != nil {
+			t.Fatalf("%s: %s", v.kind, err)
+		}
+		if !reflect.DeepEqual(x, v.value) {
+			t.Fatalf("%s: expect %v, got %v", v.kind, v.value, x)
+		}
+	}
+}
+
+func TestKindInvalid(t *testing.T) {
+	if _, err := KindIP.Decode([]byte{1, 2, 3}); err != ErrInvalidOption {
+		t.Fatalf("expect %v, got %v", ErrInvalidOption, err)
+	}
+	if _, err := KindUint16.Encode("foo"); err == nil {
+		t.Fatal("invalid value accepted")
+	}
+}
+
+func TestSetValueNotIPv4(t *testing.T) {
+	p := NewDiscoverPacket()
+	for _, ip := range []net.IP{net.ParseIP("2001:db8::1"), net.IP{1, 2, 3}} {
+		if err := p.SetValue(ServerIdentifier, ip); err != ErrInvalidOption {
+			t.Fatalf("%v: expect %v, got %v", ip, ErrInvalidOption, err)
+		}
+	}
+	if _, ok := p.Option(ServerIdentifier); ok {
+		t.Fatal("option set from invalid address")
+	}
+}
+
+func TestDomainListCompressed(t *testing.T) {
+	// RFC 3397 section 3 example
+	b := []byte{3, 'e', 'n', 'g', 5, 'a', 'p', 'p', 'l', 'e', 3, 'c', 'o',
+		'm', 0, 3, 'm', 'a', 'c', 0xc0, 4}
+
+	list, err := decodeDomainList(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{"eng.apple.com", "mac.apple.com"}
+	if !reflect.DeepEqual(list, expect) {
+		t.Fatalf("expect %v, got %v", expect, list)
+	}
+}
+
+func TestDomainLoop(t *testing.T) {
+	b := []byte{1, 'a', 0xc0, 0}
+	if _, err := decodeDomainList(b); err != ErrInvalidDomain {
+		t.Fatalf("expect %v, got %v", ErrInvalidDomain, err)
+	}
+}
+
+func TestSetValue(t *testing.T) {
+	p := NewDiscoverPacket()
+	if err := p.SetValue(InterfaceMTU, uint16(1500)); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := p.Option(InterfaceMTU)
+	if !bytes.Equal(b, []byte{0x05, 0xdc}) {
+		t.Fatalf("expect %v, got %v", []byte{0x05, 0xdc}, b)
+	}
+}
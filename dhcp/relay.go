@@ -0,0 +1,139 @@
+package dhcp
+
+import (
+	"fmt"
+	"net"
+)
+
+// Relay Agent Information sub-options
+const (
+	AgentCircuitID        = 1  // RFC 3046
+	AgentRemoteID         = 2  // RFC 3046
+	AgentLinkSelection    = 5  // RFC 3527
+	AgentSubscriberID     = 6  // RFC 3993
+	AgentVendorSpecific   = 9  // RFC 4243
+	AgentServerIDOverride = 11 // RFC 5107
+)
+
+var agentNames = map[byte]string{
+	AgentCircuitID:        "Circuit ID",
+	AgentRemoteID:         "Remote ID",
+	AgentLinkSelection:    "Link Selection",
+	AgentSubscriberID:     "Subscriber ID",
+	AgentVendorSpecific:   "Vendor Specific",
+	AgentServerIDOverride: "Server ID Override",
+}
+
+// AgentSubOptionName returns the name of a Relay Agent Information
+// sub-option.
+func AgentSubOptionName(code byte) string {
+	if s, ok := agentNames[code]; ok {
+		return s
+	}
+	return fmt.Sprintf("Sub-option %d", code)
+}
+
+// RelayAgentInfo is the value of the Relay Agent Information option
+// (RFC 3046). Sub-options not present are nil or empty.
+type RelayAgentInfo struct {
+	CircuitID        []byte
+	RemoteID         []byte
+	LinkSelection    net.IP
+	SubscriberID     string
+	ServerIDOverride net.IP
+	VendorSpecific   []byte
+	Other            []Option // unknown sub-options
+}
+
+// ParseRelayAgentInfo decodes the data of a Relay Agent Information
+// option.
+func ParseRelayAgentInfo(b []byte) (*RelayAgentInfo, error) {
+	subs, err := decodeSubOptions(b)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &RelayAgentInfo{}
+	for _, o := range subs {
+		data := append([]byte{}, o.Data...)
+		switch o.Type {
+		case AgentCircuitID:
+			r.CircuitID = data
+		case AgentRemoteID:
+			r.RemoteID = data
+		case AgentLinkSelection, AgentServerIDOverride:
+			if len(data) != 4 {
+				return nil, ErrInvalidOption
+			}
+			ip := net.IP(data)
+			if o.Type == AgentLinkSelection {
+				r.LinkSelection = ip
+			} else {
+				r.ServerIDOverride = ip
+			}
+		case AgentSubscriberID:
+			r.SubscriberID = string(data)
+		case AgentVendorSpecific:
+			r.VendorSpecific = data
+		default:
+			r.Other = append(r.Other, Option{o.Type, data})
+		}
+	}
+
+	return r, nil
+}
+
+// SubOptions returns the sub-options in the order they are encoded.
+func (r *RelayAgentInfo) SubOptions() []Option {
+	var subs []Option
+	if r.CircuitID != nil {
+		subs = append(subs, Option{AgentCircuitID, r.CircuitID})
+	}
+	if r.RemoteID != nil {
+		subs = append(subs, Option{AgentRemoteID, r.RemoteID})
+	}
+	if r.LinkSelection != nil {
+		subs = append(subs, Option{AgentLinkSelection, r.LinkSelection.To4()})
+	}
+	if r.SubscriberID != "" {
+		subs = append(subs, Option{AgentSubscriberID, []byte(r.SubscriberID)})
+	}
+	if r.VendorSpecific != nil {
+		subs = append(subs, Option{AgentVendorSpecific, r.VendorSpecific})
+	}
+	if r.ServerIDOverride != nil {
+		subs = append(subs, Option{AgentServerIDOverride, r.ServerIDOverride.To4()})
+	}
+	return append(subs, r.Other...)
+}
+
+// Encode returns the data of the Relay Agent Information option.
+func (r *RelayAgentInfo) Encode() ([]byte, error) {
+	for _, ip := range []net.IP{r.LinkSelection, r.ServerIDOverride} {
+		if ip != nil && ip.To4() == nil {
+			return nil, fmt.Errorf("dhcp: %s: not an IPv4 address", ip)
+		}
+	}
+	return encodeSubOptions(r.SubOptions())
+}
+
+// RelayAgentInfo returns the Relay Agent Information option of the packet,
+// or nil if not present.
+func (p *Packet) RelayAgentInfo() (*RelayAgentInfo, error) {
+	b, ok := p.Option(RelayAgentInformation)
+	if !ok {
+		return nil, nil
+	}
+	return ParseRelayAgentInfo(b)
+}
+
+// SetRelayAgentInfo adds a Relay Agent Information option to the packet,
+// after the options already present. RFC 3046 requires it to be the last
+// option, so it should be set after all other options.
+func (p *Packet) SetRelayAgentInfo(r *RelayAgentInfo) error {
+	b, err := r.Encode()
+	if err != nil {
+		return err
+	}
+	return p.SetOption(RelayAgentInformation, b)
+}
@@ -0,0 +1,48 @@
+package dhcp
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestRelayAgentInfo(t *testing.T) {
+	p := NewDiscoverPacket()
+	r := &RelayAgentInfo{
+		CircuitID:     []byte("eth0/1"),
+		RemoteID:      []byte{0, 1, 2, 3, 4, 5},
+		LinkSelection: net.IPv4(10, 0, 0, 0),
+		Other:         []Option{{151, []byte{1}}},
+	}
+	if err := p.SetRelayAgentInfo(r); err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := p.Option(RelayAgentInformation)
+	expect := []byte{
+		AgentCircuitID, 6, 'e', 't', 'h', '0', '/', '1',
+		AgentRemoteID, 6, 0, 1, 2, 3, 4, 5,
+		AgentLinkSelection, 4, 10, 0, 0, 0,
+		151, 1, 1,
+	}
+	if !bytes.Equal(b, expect) {
+		t.Fatalf("expect %v, got %v", expect, b)
+	}
+
+	x, err := p.RelayAgentInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(x.CircuitID) != "eth0/1" || !x.LinkSelection.Equal(r.LinkSelection) {
+		t.Fatalf("expect %v, got %v", r, x)
+	}
+	if len(x.Other) != 1 || x.Other[0].Type != 151 {
+		t.Fatalf("expect unknown sub-option 151, got %v", x.Other)
+	}
+}
+
+func TestRelayAgentInfoCorrupted(t *testing.T) {
+	if _, err := ParseRelayAgentInfo([]byte{AgentCircuitID, 4, 1}); err != ErrCorruptedOptions {
+		t.Fatalf("expect %v, got %v", ErrCorruptedOptions, err)
+	}
+}
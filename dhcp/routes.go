@@ -0,0 +1,107 @@
+package dhcp
+
+import (
+	"fmt"
+	"net"
+)
+
+// Route is a static route set by the Static Route or Classless Static
+// Route options.
+type Route struct {
+	Dest    *net.IPNet
+	Gateway net.IP
+}
+
+func (r Route) String() string {
+	return fmt.Sprintf("%s via %s", r.Dest, r.Gateway)
+}
+
+// ParseClasslessRoutes decodes the data of a Classless Static Route
+// option (RFC 3442). Each route is encoded as the prefix length, the
+// significant octets of the destination and the router address.
+func ParseClasslessRoutes(b []byte) ([]Route, error) {
+	var routes []Route
+	for i := 0; i < len(b); {
+		bits := int(b[i])
+		if bits > 32 {
+			return routes, ErrInvalidOption
+		}
+		n := (bits + 7) / 8
+		if i+1+n+4 > len(b) {
+			return routes, ErrInvalidOption
+		}
+
+		dest := make(net.IP, 4)
+		copy(dest, b[i+1:i+1+n])
+		mask := net.CIDRMask(bits, 32)
+		gw := b[i+1+n : i+1+n+4]
+
+		routes = append(routes, Route{
+			Dest:    &net.IPNet{IP: dest.Mask(mask), Mask: mask},
+			Gateway: net.IPv4(gw[0], gw[1], gw[2], gw[3]),
+		})
+		i += 1 + n + 4
+	}
+	return routes, nil
+}
+
+// EncodeClasslessRoutes returns the data of a Classless Static Route
+// option for the given routes.
+func EncodeClasslessRoutes(routes []Route) ([]byte, error) {
+	var b []byte
+	for _, r := range routes {
+		dest, gw := r.Dest.IP.To4(), r.Gateway.To4()
+		bits, size := r.Dest.Mask.Size()
+		if dest == nil || gw == nil || size != 32 {
+			return nil, fmt.Errorf("dhcp: %s: not an IPv4 route", r)
+		}
+		b = append(b, byte(bits))
+		b = append(b, dest[:(bits+7)/8]...)
+		b = append(b, gw...)
+	}
+	return b, nil
+}
+
+// ParseStaticRoutes decodes the data of the Static Route option (RFC 2132
+// section 5.8). Destinations are classful, so the mask is derived from
+// the destination address class. The default route is illegal in this
+// option, but is decoded as such to let callers detect it.
+func ParseStaticRoutes(b []byte) ([]Route, error) {
+	if len(b) == 0 || len(b)%8 != 0 {
+		return nil, ErrInvalidOption
+	}
+
+	var routes []Route
+	list := ipList(b)
+	for i := 0; i < len(list); i += 2 {
+		dest := list[i].To4()
+		mask := dest.DefaultMask()
+		if dest.Equal(net.IPv4zero) {
+			mask = net.CIDRMask(0, 32)
+		} else if mask == nil {
+			mask = net.CIDRMask(32, 32)
+		}
+		routes = append(routes, Route{
+			Dest:    &net.IPNet{IP: dest.Mask(mask), Mask: mask},
+			Gateway: list[i+1],
+		})
+	}
+	return routes, nil
+}
+
+// Routes returns the static routes set in the packet. As specified in RFC
+// 3442, the Static Route option is ignored if the Classless Static Route
+// option is present. The Microsoft variant of the classless option is
+// used if the standard option is not present.
+func (p *Packet) Routes() ([]Route, error) {
+	if b, ok := p.Option(ClasslessStaticRoute); ok {
+		return ParseClasslessRoutes(b)
+	}
+	if b, ok := p.Option(MSClasslessStaticRoute); ok {
+		return ParseClasslessRoutes(b)
+	}
+	if b, ok := p.Option(StaticRoute); ok {
+		return ParseStaticRoutes(b)
+	}
+	return nil, nil
+}
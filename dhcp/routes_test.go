@@ -0,0 +1,71 @@
+package dhcp
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestClasslessRoutes(t *testing.T) {
+	b := []byte{
+		0, 192, 168, 0, 1, // default route
+		24, 10, 1, 2, 192, 168, 0, 2,
+		1, 128, 10, 0, 0, 1,
+	}
+
+	routes, err := ParseClasslessRoutes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []string{
+		"0.0.0.0/0 via 192.168.0.1",
+		"10.1.2.0/24 via 192.168.0.2",
+		"128.0.0.0/1 via 10.0.0.1",
+	}
+	if len(routes) != len(expect) {
+		t.Fatalf("expect %d routes, got %d", len(expect), len(routes))
+	}
+	for i, r := range routes {
+		if r.String() != expect[i] {
+			t.Fatalf("expect %q, got %q", expect[i], r)
+		}
+	}
+
+	x, err := EncodeClasslessRoutes(routes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(x, b) {
+		t.Fatalf("expect %v, got %v", b, x)
+	}
+}
+
+func TestClasslessRoutesInvalid(t *testing.T) {
+	if _, err := ParseClasslessRoutes([]byte{33, 1, 2, 3, 4, 5, 6, 7, 8}); err != ErrInvalidOption {
+		t.Fatalf("expect %v, got %v", ErrInvalidOption, err)
+	}
+	if _, err := ParseClasslessRoutes([]byte{24, 10, 1, 2, 192}); err != ErrInvalidOption {
+		t.Fatalf("expect %v, got %v", ErrInvalidOption, err)
+	}
+}
+
+func TestPacketRoutes(t *testing.T) {
+	p := NewDiscoverPacket()
+	p.SetIPs(StaticRoute, []net.IP{net.IPv4(10, 0, 0, 0), net.IPv4(192, 168, 0, 1)})
+
+	routes, err := p.Routes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 1 || routes[0].String() != "10.0.0.0/8 via 192.168.0.1" {
+		t.Fatalf("unexpected routes %v", routes)
+	}
+
+	// classless routes take precedence
+	p.SetOption(ClasslessStaticRoute, []byte{16, 172, 16, 192, 168, 0, 1})
+	routes, _ = p.Routes()
+	if len(routes) != 1 || routes[0].String() != "172.16.0.0/16 via 192.168.0.1" {
+		t.Fatalf("unexpected routes %v", routes)
+	}
+}
@@ -0,0 +1,242 @@
+package dhcp
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+var ErrPeerClosed = errors.New("dhcp: peer closed")
+
+// Segment is an in-memory network segment connecting peers in the same
+// process. Packets can be dropped or delayed to simulate real networks,
+// which allows testing clients and servers without privileged sockets.
+type Segment struct {
+	mu      sync.Mutex
+	peers   []*SegmentPeer
+	loss    float64
+	latency time.Duration
+	rnd     *rand.Rand
+}
+
+// NewSegment returns a segment without packet loss or latency.
+func NewSegment() *Segment {
+	return &Segment{rnd: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// SetLoss sets the probability of a packet being dropped, from 0 to 1.
+func (s *Segment) SetLoss(p float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loss = p
+}
+
+// SetLatency sets the time packets take to be delivered.
+func (s *Segment) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// SetSeed sets the seed of the packet loss generator.
+func (s *Segment) SetSeed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rnd = rand.New(rand.NewSource(seed))
+}
+
+// NewClient attaches a client without an IP address to the segment.
+func (s *Segment) NewClient(mac net.HardwareAddr) *SegmentPeer {
+	return s.attach(net.IPv4zero, mac, ClientPort, ServerPort)
+}
+
+// NewServer attaches a server with the given addresses to the segment.
+func (s *Segment) NewServer(ip net.IP, mac net.HardwareAddr) *SegmentPeer {
+	return s.attach(ip, mac, ServerPort, ClientPort)
+}
+
+func (s *Segment) attach(ip net.IP, mac net.HardwareAddr, localPort, remotePort int) *SegmentPeer {
+	sp := &SegmentPeer{
+		seg:        s,
+		ip:         ip,
+		mac:        mac,
+		localPort:  localPort,
+		remotePort: remotePort,
+		queue:      make(chan segmentFrame, 64),
+		done:       make(chan struct{}),
+	}
+
+	s.mu.Lock()
+	s.peers = append(s.peers, sp)
+	s.mu.Unlock()
+
+	return sp
+}
+
+func (s *Segment) detach(sp *SegmentPeer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, x := range s.peers {
+		if x == sp {
+			s.peers = append(s.peers[:i], s.peers[i+1:]...)
+			return
+		}
+	}
+}
+
+// transmit delivers data from a peer to the peers listening on the port
+// with the given address, or to all of them if ip is the broadcast address.
+func (s *Segment) transmit(from *SegmentPeer, ip net.IP, port int, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	broadcast := ip.Equal(net.IPv4bcast)
+
+	for _, sp := range s.peers {
+		if sp == from || sp.localPort != port {
+			continue
+		}
+		if !broadcast && !sp.Address().Equal(ip) {
+			continue
+		}
+		if s.loss > 0 && s.rnd.Float64() < s.loss {
+			continue
+		}
+
+		f := segmentFrame{
+			src:  &net.UDPAddr{IP: from.Address(), Port: from.localPort},
+			mac:  from.mac,
+			data: append([]byte{}, data...),
+		}
+		if s.latency > 0 {
+			to := sp
+			time.AfterFunc(s.latency, func() { to.deliver(f) })
+		} else {
+			sp.deliver(f)
+		}
+	}
+}
+
+type segmentFrame struct {
+	src  *net.UDPAddr
+	mac  net.HardwareAddr
+	data []byte
+}
+
+// SegmentPeer is a peer attached to a Segment.
+type SegmentPeer struct {
+	seg        *Segment
+	mu         sync.Mutex
+	ip         net.IP
+	mac        net.HardwareAddr
+	lastMAC    net.HardwareAddr
+	localPort  int
+	remotePort int
+	remote     net.IP
+	queue      chan segmentFrame
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+// Address returns the IP address of the peer.
+func (sp *SegmentPeer) Address() net.IP {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	return sp.ip
+}
+
+// SetAddress sets the IP address of the peer, as a client does after
+// obtaining a lease.
+func (sp *SegmentPeer) SetAddress(ip net.IP) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.ip = ip
+}
+
+// SetRemote sets the address of the peer packets are sent to.
+func (sp *SegmentPeer) SetRemote(ip net.IP) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.remote = ip
+}
+
+func (sp *SegmentPeer) deliver(f segmentFrame) {
+	select {
+	case <-sp.done:
+	case sp.queue <- f:
+	default:
+		// receive buffer full, drop packet
+	}
+}
+
+func (sp *SegmentPeer) Send(ctx context.Context, p *Packet) error {
+	sp.mu.Lock()
+	remote := sp.remote
+	sp.mu.Unlock()
+
+	if remote == nil {
+		return errors.New("dhcp: remote address not set")
+	}
+	return sp.send(ctx, p, remote)
+}
+
+func (sp *SegmentPeer) Broadcast(ctx context.Context, p *Packet) error {
+	return sp.send(ctx, p, net.IPv4bcast)
+}
+
+func (sp *SegmentPeer) send(ctx context.Context, p *Packet, ip net.IP) error {
+	select {
+	case <-sp.done:
+		return ErrPeerClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	data, err := p.Encode(0)
+	if err != nil {
+		return err
+	}
+
+	sp.seg.transmit(sp, ip, sp.remotePort, data)
+
+	return nil
+}
+
+func (sp *SegmentPeer) Receive(ctx context.Context) (Packet, *net.UDPAddr, error) {
+	var p Packet
+
+	select {
+	case f := <-sp.queue:
+		sp.mu.Lock()
+		sp.lastMAC = f.mac
+		sp.mu.Unlock()
+		err := p.Decode(f.data)
+		return p, f.src, err
+	case <-ctx.Done():
+		return p, nil, ctx.Err()
+	case <-sp.done:
+		return p, nil, ErrPeerClosed
+	}
+}
+
+// RemoteMAC returns the hardware address of the sender of the last
+// packet received.
+func (sp *SegmentPeer) RemoteMAC() net.HardwareAddr {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	return sp.lastMAC
+}
+
+// Close detaches the peer from the segment. Pending and future calls to
+// Receive return ErrPeerClosed.
+func (sp *SegmentPeer) Close() error {
+	sp.closeOnce.Do(func() {
+		sp.seg.detach(sp)
+		close(sp.done)
+	})
+	return nil
+}
@@ -0,0 +1,195 @@
+package dhcp
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+// offerServer answers discovers and requests received by the peer until
+// it's closed.
+func offerServer(sv *SegmentPeer, lease IPv4Address) {
+	for {
+		req, _, err := sv.Receive(context.Background())
+		if err != nil {
+			return
+		}
+
+		var t byte
+		switch req.MessageType() {
+		case DHCPDiscover:
+			t = DHCPOffer
+		case DHCPRequest:
+			t = DHCPAck
+		default:
+			continue
+		}
+
+		p := &Packet{
+			Op:     BootReply,
+			Htype:  req.Htype,
+			Hlen:   req.Hlen,
+			Xid:    req.Xid,
+			Yiaddr: lease,
+			Chaddr: req.Chaddr,
+		}
+		p.SetOption(DHCPMessageType, []byte{t})
+		p.SetIP(ServerIdentifier, sv.Address())
+		p.SetUint32(IPAddressLeaseTime, 3600)
+		sv.Broadcast(context.Background(), p)
+	}
+}
+
+func newTestSegment(t *testing.T) (*Segment, *SegmentPeer) {
+	seg := NewSegment()
+	seg.SetSeed(1)
+
+	cl := seg.NewClient(net.HardwareAddr{1, 2, 3, 4, 5, 6})
+	t.Cleanup(func() { cl.Close() })
+
+	for i, ip := range []net.IP{net.IPv4(10, 0, 0, 1), net.IPv4(10, 0, 0, 2)} {
+		sv := seg.NewServer(ip, net.HardwareAddr{0, 0, 0, 0, 0, byte(i + 1)})
+		t.Cleanup(func() { sv.Close() })
+		go offerServer(sv, IPv4Address{10, 0, 0, byte(100 + i)})
+	}
+
+	return seg, cl
+}
+
+// collectOffers broadcasts a discover and returns the servers answering
+// before the timeout.
+func collectOffers(t *testing.T, cl *SegmentPeer, timeout time.Duration) map[string]net.HardwareAddr {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	p := NewDiscoverPacket()
+	p.SetClientMAC("01:02:03:04:05:06")
+	if err := cl.Broadcast(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+
+	offers := map[string]net.HardwareAddr{}
+	for {
+		o, remote, err := cl.Receive(ctx)
+		if err == context.DeadlineExceeded {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if o.Xid != p.Xid || o.MessageType() != DHCPOffer {
+			continue
+		}
+		offers[remote.IP.String()] = cl.RemoteMAC()
+	}
+	return offers
+}
+
+func TestSegmentDiscover(t *testing.T) {
+	_, cl := newTestSegment(t)
+
+	offers := collectOffers(t, cl, 100*time.Millisecond)
+	if len(offers) != 2 {
+		t.Fatalf("expect 2 offers, got %d", len(offers))
+	}
+	if mac := offers["10.0.0.2"]; mac.String() != "00:00:00:00:00:02" {
+		t.Fatalf("unexpected server MAC %s", mac)
+	}
+}
+
+func TestSegmentRequest(t *testing.T) {
+	_, cl := newTestSegment(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	d := NewDiscoverPacket()
+	d.SetClientMAC("01:02:03:04:05:06")
+	cl.Broadcast(ctx, d)
+
+	o, _, err := cl.Receive(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cl.SetRemote(o.ServerID())
+	if err := cl.Send(ctx, NewRequestPacket(&o)); err != nil {
+		t.Fatal(err)
+	}
+
+	for {
+		a, remote, err := cl.Receive(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.MessageType() != DHCPAck {
+			continue
+		}
+		if !remote.IP.Equal(o.ServerID()) || a.Yiaddr != o.Yiaddr {
+			t.Fatalf("ack from %s for %s, expect %s for %s",
+				remote.IP, a.Yiaddr.String(), o.ServerID(), o.Yiaddr.String())
+		}
+		break
+	}
+}
+
+func TestSegmentLoss(t *testing.T) {
+	seg, cl := newTestSegment(t)
+	seg.SetLoss(1)
+
+	if offers := collectOffers(t, cl, 50*time.Millisecond); len(offers) != 0 {
+		t.Fatalf("expect no offers, got %d", len(offers))
+	}
+}
+
+func TestSegmentLatency(t *testing.T) {
+	seg, cl := newTestSegment(t)
+	seg.SetLatency(20 * time.Millisecond)
+
+	start := time.Now()
+	offers := collectOffers(t, cl, 200*time.Millisecond)
+	if len(offers) != 2 {
+		t.Fatalf("expect 2 offers, got %d", len(offers))
+	}
+
+	// too short for a round trip
+	if offers := collectOffers(t, cl, 30*time.Millisecond); len(offers) != 0 {
+		t.Fatalf("expect no offers, got %d", len(offers))
+	}
+
+	if time.Since(start) < 40*time.Millisecond {
+		t.Fatal("packets delivered without latency")
+	}
+}
+
+func TestSegmentCancel(t *testing.T) {
+	_, cl := newTestSegment(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	if _, _, err := cl.Receive(ctx); err != context.Canceled {
+		t.Fatalf("expect %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestSegmentClose(t *testing.T) {
+	seg := NewSegment()
+	cl := seg.NewClient(net.HardwareAddr{1, 2, 3, 4, 5, 6})
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cl.Close()
+	}()
+
+	if _, _, err := cl.Receive(context.Background()); err != ErrPeerClosed {
+		t.Fatalf("expect %v, got %v", ErrPeerClosed, err)
+	}
+	if err := cl.Broadcast(context.Background(), NewDiscoverPacket()); err != ErrPeerClosed {
+		t.Fatalf("expect %v, got %v", ErrPeerClosed, err)
+	}
+}
@@ -0,0 +1,190 @@
+package dhcp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// VendorOption is a sub-option of the Vendor Specific Information option.
+type VendorOption struct {
+	Code  byte
+	Name  string
+	Data  []byte
+	Value interface{} // decoded value, or Data if unknown
+}
+
+// A VendorDecoder decodes Vendor Specific Information option data for a
+// vendor class.
+type VendorDecoder interface {
+	DecodeVendor(data []byte) ([]VendorOption, error)
+}
+
+// SubOptionInfo describes a vendor sub-option.
+type SubOptionInfo struct {
+	Name string
+	Kind Kind
+}
+
+// EncapsulatedDecoder decodes vendor information encapsulated as
+// sub-options (RFC 2132 section 8.4), as described in the table.
+type EncapsulatedDecoder map[byte]SubOptionInfo
+
+func (d EncapsulatedDecoder) DecodeVendor(data []byte) ([]VendorOption, error) {
+	opts, _, err := decodeOptions(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []VendorOption
+	for _, o := range opts {
+		info, ok := d[o.Type]
+		if !ok {
+			info = SubOptionInfo{fmt.Sprintf("Sub-option %d", o.Type), KindBytes}
+		}
+		v, err := info.Kind.Decode(o.Data)
+		if err != nil {
+			v = o.Data
+		}
+		list = append(list, VendorOption{o.Type, info.Name, o.Data, v})
+	}
+	return list, nil
+}
+
+var vendorDecoders = map[string]VendorDecoder{}
+
+// RegisterVendorDecoder sets the decoder used for vendor classes starting
+// with the given prefix.
+func RegisterVendorDecoder(prefix string, d VendorDecoder) {
+	vendorDecoders[prefix] = d
+}
+
+// LookupVendorDecoder returns the decoder registered with the longest
+// prefix matching the vendor class. A decoder for unnamed encapsulated
+// sub-options is returned if no decoder matches.
+func LookupVendorDecoder(class string) VendorDecoder {
+	var d VendorDecoder = EncapsulatedDecoder{}
+	n := -1
+	for prefix, x := range vendorDecoders {
+		if strings.HasPrefix(class, prefix) && len(prefix) > n {
+			d, n = x, len(prefix)
+		}
+	}
+	return d
+}
+
+// DecodeVendorSpecific decodes Vendor Specific Information option data
+// for the given vendor class.
+func DecodeVendorSpecific(class string, data []byte) ([]VendorOption, error) {
+	return LookupVendorDecoder(class).DecodeVendor(data)
+}
+
+// VendorOptions decodes the Vendor Specific Information option of the
+// packet, using the Vendor Class Identifier option to select the decoder.
+func (p *Packet) VendorOptions() ([]VendorOption, error) {
+	data, ok := p.Option(VendorSpecific)
+	if !ok {
+		return nil, nil
+	}
+	class, _ := p.Option(VendorClassIdentifier)
+	return DecodeVendorSpecific(string(class), data)
+}
+
+// PXE vendor options (PXE specification 2.1)
+const (
+	PXEMTFTPIP            = 1
+	PXEMTFTPClientPort    = 2
+	PXEMTFTPServerPort    = 3
+	PXEMTFTPTimeout       = 4
+	PXEMTFTPDelay         = 5
+	PXEDiscoveryControl   = 6
+	PXEDiscoveryMcastAddr = 7
+	PXEBootServers        = 8
+	PXEBootMenu           = 9
+	PXEMenuPrompt         = 10
+	PXEBootItem           = 71
+)
+
+// PXEMenuItem is an entry of the PXE boot menu.
+type PXEMenuItem struct {
+	Type        uint16
+	Description string
+}
+
+func (m PXEMenuItem) String() string {
+	return fmt.Sprintf("%#04x %q", m.Type, m.Description)
+}
+
+// PXEPrompt is the PXE boot menu prompt.
+type PXEPrompt struct {
+	Timeout byte
+	Prompt  string
+}
+
+func (m PXEPrompt) String() string {
+	return fmt.Sprintf("%q (timeout %d)", m.Prompt, m.Timeout)
+}
+
+type pxeDecoder struct {
+	EncapsulatedDecoder
+}
+
+func (d pxeDecoder) DecodeVendor(data []byte) ([]VendorOption, error) {
+	list, err := d.EncapsulatedDecoder.DecodeVendor(data)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, o := range list {
+		switch o.Code {
+		case PXEBootMenu:
+			var menu []PXEMenuItem
+			b := o.Data
+			for len(b) >= 3 && len(b) >= 3+int(b[2]) {
+				menu = append(menu, PXEMenuItem{
+					binary.BigEndian.Uint16(b),
+					string(b[3 : 3+int(b[2])]),
+				})
+				b = b[3+int(b[2]):]
+			}
+			if len(b) == 0 {
+				list[i].Value = menu
+			}
+		case PXEMenuPrompt:
+			if len(o.Data) >= 1 {
+				list[i].Value = PXEPrompt{o.Data[0], string(o.Data[1:])}
+			}
+		}
+	}
+
+	return list, nil
+}
+
+// Microsoft vendor options
+const (
+	MSFTNetBIOSDisable          = 1
+	MSFTReleaseOnShutdown       = 2
+	MSFTDefaultRouterMetricBase = 3
+)
+
+func init() {
+	RegisterVendorDecoder("PXEClient", pxeDecoder{EncapsulatedDecoder{
+		PXEMTFTPIP:            {"MTFTP IP Address", KindIP},
+		PXEMTFTPClientPort:    {"MTFTP Client Port", KindUint16},
+		PXEMTFTPServerPort:    {"MTFTP Server Port", KindUint16},
+		PXEMTFTPTimeout:       {"MTFTP Timeout", KindUint8},
+		PXEMTFTPDelay:         {"MTFTP Delay", KindUint8},
+		PXEDiscoveryControl:   {"Discovery Control", KindUint8},
+		PXEDiscoveryMcastAddr: {"Discovery Multicast", KindIP},
+		PXEBootServers:        {"Boot Servers", KindBytes},
+		PXEBootMenu:           {"Boot Menu", KindBytes},
+		PXEMenuPrompt:         {"Menu Prompt", KindBytes},
+		PXEBootItem:           {"Boot Item", KindBytes},
+	}})
+
+	RegisterVendorDecoder("MSFT", EncapsulatedDecoder{
+		MSFTNetBIOSDisable:          {"NetBIOS Disable", KindUint32},
+		MSFTReleaseOnShutdown:       {"Release On Shutdown", KindUint32},
+		MSFTDefaultRouterMetricBase: {"Router Metric Base", KindUint32},
+	})
+}
@@ -0,0 +1,80 @@
+package dhcp
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestVendorPXE(t *testing.T) {
+	p := NewDiscoverPacket()
+	p.SetString(VendorClassIdentifier, "PXEClient")
+	p.SetOption(VendorSpecific, []byte{
+		PXEDiscoveryControl, 1, 3,
+		PXEBootMenu, 9, 0x80, 0x00, 6, 'L', 'i', 'n', 'u', 'x', '!',
+		PXEMenuPrompt, 4, 10, 'G', 'o', '!',
+		PXEMTFTPIP, 4, 224, 1, 2, 3,
+		EndOption,
+	})
+
+	opts, err := p.VendorOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []interface{}{
+		byte(3),
+		[]PXEMenuItem{{0x8000, "Linux!"}},
+		PXEPrompt{10, "Go!"},
+		net.IPv4(224, 1, 2, 3),
+	}
+	if len(opts) != len(expect) {
+		t.Fatalf("expect %d sub-options, got %d", len(expect), len(opts))
+	}
+	for i, o := range opts {
+		if !reflect.DeepEqual(o.Value, expect[i]) {
+			t.Fatalf("%s: expect %v, got %v", o.Name, expect[i], o.Value)
+		}
+	}
+}
+
+func TestVendorMSFT(t *testing.T) {
+	opts, err := DecodeVendorSpecific("MSFT 5.0", []byte{
+		MSFTReleaseOnShutdown, 4, 0, 0, 0, 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opts) != 1 || opts[0].Name != "Release On Shutdown" ||
+		opts[0].Value != uint32(1) {
+		t.Fatalf("unexpected sub-options %v", opts)
+	}
+}
+
+type testDecoder struct{}
+
+func (testDecoder) DecodeVendor(data []byte) ([]VendorOption, error) {
+	return []VendorOption{{0, "Test", data, string(data)}}, nil
+}
+
+func TestVendorRegister(t *testing.T) {
+	RegisterVendorDecoder("test", testDecoder{})
+	defer delete(vendorDecoders, "test")
+
+	opts, err := DecodeVendorSpecific("test-1.0", []byte("foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opts) != 1 || opts[0].Value != "foo" {
+		t.Fatalf("unexpected sub-options %v", opts)
+	}
+
+	// unknown classes use unnamed sub-options
+	opts, err = DecodeVendorSpecific("other", []byte{1, 1, 'x'})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opts) != 1 || opts[0].Name != "Sub-option 1" {
+		t.Fatalf("unexpected sub-options %v", opts)
+	}
+}
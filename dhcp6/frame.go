@@ -0,0 +1,97 @@
+package dhcp6
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+)
+
+const (
+	etherHeaderSize = 14
+	ipHeaderSize    = 40
+	udpHeaderSize   = 8
+
+	etherTypeIPv6 = 0x86dd
+	etherTypeVLAN = 0x8100
+	protocolUDP   = 17
+
+	// extension headers skipped before the UDP header
+	hopByHop    = 0
+	routing     = 43
+	destOptions = 60
+)
+
+var errNotUDP = errors.New("dhcp6: not an IPv6 UDP datagram")
+
+// frame is a UDP datagram with its Ethernet and IPv6 headers.
+type frame struct {
+	srcMAC  net.HardwareAddr
+	srcIP   net.IP
+	dstIP   net.IP
+	srcPort int
+	dstPort int
+	payload []byte
+}
+
+// isDHCP reports whether the frame is sent between DHCPv6 ports.
+func (f *frame) isDHCP() bool {
+	switch f.dstPort {
+	case ClientPort, ServerPort:
+		return f.srcPort == ClientPort || f.srcPort == ServerPort
+	}
+	return false
+}
+
+// decodeFrame parses an Ethernet frame containing an IPv6 UDP datagram.
+// 802.1Q VLAN tags and the common extension headers are skipped.
+func decodeFrame(b []byte) (*frame, error) {
+	if len(b) < etherHeaderSize {
+		return nil, errNotUDP
+	}
+	f := &frame{srcMAC: net.HardwareAddr(append([]byte{}, b[6:12]...))}
+
+	i := 12
+	for len(b) >= i+2 && binary.BigEndian.Uint16(b[i:]) == etherTypeVLAN {
+		i += 4
+	}
+	if len(b) < i+2 || binary.BigEndian.Uint16(b[i:]) != etherTypeIPv6 {
+		return nil, errNotUDP
+	}
+
+	ip := b[i+2:]
+	if len(ip) < ipHeaderSize || ip[0]>>4 != 6 {
+		return nil, errNotUDP
+	}
+	pl := int(binary.BigEndian.Uint16(ip[4:]))
+	if ipHeaderSize+pl > len(ip) {
+		return nil, errNotUDP
+	}
+	f.srcIP = net.IP(append([]byte{}, ip[8:24]...))
+	f.dstIP = net.IP(append([]byte{}, ip[24:40]...))
+
+	next := ip[6]
+	udp := ip[ipHeaderSize : ipHeaderSize+pl]
+	for next == hopByHop || next == routing || next == destOptions {
+		if len(udp) < 8 {
+			return nil, errNotUDP
+		}
+		n := (int(udp[1]) + 1) * 8
+		if n > len(udp) {
+			return nil, errNotUDP
+		}
+		next, udp = udp[0], udp[n:]
+	}
+	if next != protocolUDP || len(udp) < udpHeaderSize {
+		return nil, errNotUDP
+	}
+
+	ul := int(binary.BigEndian.Uint16(udp[4:]))
+	if ul < udpHeaderSize || ul > len(udp) {
+		return nil, errNotUDP
+	}
+	f.srcPort = int(binary.BigEndian.Uint16(udp[0:]))
+	f.dstPort = int(binary.BigEndian.Uint16(udp[2:]))
+	f.payload = append([]byte{}, udp[udpHeaderSize:ul]...)
+
+	return f, nil
+}
@@ -0,0 +1,76 @@
+package dhcp6
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+)
+
+// buildFrame returns an Ethernet frame with an IPv6 UDP datagram, with a
+// hop-by-hop options header if hbh is set.
+func buildFrame(src, dst net.IP, sport, dport int, payload []byte, hbh bool) []byte {
+	udp := make([]byte, udpHeaderSize+len(payload))
+	binary.BigEndian.PutUint16(udp[0:], uint16(sport))
+	binary.BigEndian.PutUint16(udp[2:], uint16(dport))
+	binary.BigEndian.PutUint16(udp[4:], uint16(len(udp)))
+	copy(udp[udpHeaderSize:], payload)
+
+	next := byte(protocolUDP)
+	if hbh {
+		ext := []byte{protocolUDP, 0, 1, 4, 0, 0, 0, 0}
+		udp = append(ext, udp...)
+		next = hopByHop
+	}
+
+	ip := make([]byte, ipHeaderSize)
+	ip[0] = 0x60
+	binary.BigEndian.PutUint16(ip[4:], uint16(len(udp)))
+	ip[6] = next
+	ip[7] = 1
+	copy(ip[8:24], src.To16())
+	copy(ip[24:40], dst.To16())
+
+	eth := []byte{0x33, 0x33, 0, 1, 0, 2, 1, 2, 3, 4, 5, 6, 0x86, 0xdd}
+	return append(append(eth, ip...), udp...)
+}
+
+func TestDecodeFrame(t *testing.T) {
+	src := net.ParseIP("fe80::1")
+	payload := NewSolicit(net.HardwareAddr{1, 2, 3, 4, 5, 6}).Encode()
+
+	for _, hbh := range []bool{false, true} {
+		b := buildFrame(src, AllServers, ClientPort, ServerPort, payload, hbh)
+		f, err := decodeFrame(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !f.srcIP.Equal(src) || !f.dstIP.Equal(AllServers) {
+			t.Fatalf("unexpected addresses %s, %s", f.srcIP, f.dstIP)
+		}
+		if f.srcMAC.String() != "01:02:03:04:05:06" {
+			t.Fatalf("unexpected source MAC %s", f.srcMAC)
+		}
+		if !f.isDHCP() {
+			t.Fatalf("ports %d, %d not recognized as DHCPv6", f.srcPort, f.dstPort)
+		}
+		if !bytes.Equal(f.payload, payload) {
+			t.Fatal("payload mismatch")
+		}
+	}
+}
+
+func TestDecodeFrameNotDHCP(t *testing.T) {
+	b := buildFrame(net.ParseIP("fe80::1"), net.ParseIP("fe80::2"), 5353, 5353, []byte{0}, false)
+	f, err := decodeFrame(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.isDHCP() {
+		t.Fatal("mDNS datagram recognized as DHCPv6")
+	}
+
+	if _, err := decodeFrame(b[:etherHeaderSize+ipHeaderSize-1]); err != errNotUDP {
+		t.Fatalf("expect %v, got %v", errNotUDP, err)
+	}
+}
@@ -0,0 +1,202 @@
+// Package dhcp6 encodes and decodes DHCPv6 messages (RFC 8415).
+package dhcp6
+
+import (
+	"crypto/rand"
+	"errors"
+	"net"
+)
+
+// Message types
+const (
+	Solicit            = 1
+	Advertise          = 2
+	Request            = 3
+	Confirm            = 4
+	Renew              = 5
+	Rebind             = 6
+	Reply              = 7
+	Release            = 8
+	Decline            = 9
+	Reconfigure        = 10
+	InformationRequest = 11
+	RelayForw          = 12
+	RelayRepl          = 13
+)
+
+var messageNames = map[byte]string{
+	Solicit:            "SOLICIT",
+	Advertise:          "ADVERTISE",
+	Request:            "REQUEST",
+	Confirm:            "CONFIRM",
+	Renew:              "RENEW",
+	Rebind:             "REBIND",
+	Reply:              "REPLY",
+	Release:            "RELEASE",
+	Decline:            "DECLINE",
+	Reconfigure:        "RECONFIGURE",
+	InformationRequest: "INFORMATION-REQUEST",
+	RelayForw:          "RELAY-FORW",
+	RelayRepl:          "RELAY-REPL",
+}
+
+// MessageName returns the name of the message type.
+func MessageName(t byte) string {
+	if s, ok := messageNames[t]; ok {
+		return s
+	}
+	return "UNKNOWN"
+}
+
+const (
+	headerSize      = 4
+	relayHeaderSize = 34
+	maxMessageSize  = 65535
+)
+
+var (
+	ErrShortMessage = errors.New("dhcp6: message too short")
+	ErrNotRelay     = errors.New("dhcp6: not a relay message")
+)
+
+// Message is a DHCPv6 client/server or relay agent message. HopCount,
+// LinkAddress and PeerAddress are only used in relay messages.
+type Message struct {
+	Type          byte
+	TransactionID uint32 // 24 bits
+	HopCount      byte
+	LinkAddress   net.IP
+	PeerAddress   net.IP
+	Options       Options
+}
+
+// IsRelay reports whether the message is a relay agent message.
+func (m *Message) IsRelay() bool {
+	return m.Type == RelayForw || m.Type == RelayRepl
+}
+
+// NewMessage returns a message of the given type with a random
+// transaction ID.
+func NewMessage(t byte) *Message {
+	var b [3]byte
+	rand.Read(b[:])
+	return &Message{
+		Type:          t,
+		TransactionID: uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2]),
+	}
+}
+
+// NewSolicit returns a Solicit message from a client with the given
+// hardware address, requesting a non-temporary address and the DNS
+// configuration.
+func NewSolicit(mac net.HardwareAddr) *Message {
+	m := NewMessage(Solicit)
+	m.Options.Add(ClientID, DUIDFromMAC(mac))
+	m.Options.Add(ElapsedTime, []byte{0, 0})
+
+	ia := IANA{}
+	if len(mac) >= 4 {
+		copy(ia.IAID[:], mac[len(mac)-4:])
+	}
+	m.Options.Add(IANAOption, ia.Encode())
+	m.Options.Add(OptionRequest, EncodeOptionRequest(DNSServers, DomainList))
+
+	return m
+}
+
+// Encode returns the wire format of the message.
+func (m *Message) Encode() []byte {
+	var b []byte
+	if m.IsRelay() {
+		b = make([]byte, relayHeaderSize)
+		b[0] = m.Type
+		b[1] = m.HopCount
+		copy(b[2:18], m.LinkAddress.To16())
+		copy(b[18:34], m.PeerAddress.To16())
+	} else {
+		b = []byte{m.Type, byte(m.TransactionID >> 16),
+			byte(m.TransactionID >> 8), byte(m.TransactionID)}
+	}
+	return append(b, m.Options.Encode()...)
+}
+
+// Decode parses a DHCPv6 message.
+func Decode(b []byte) (*Message, error) {
+	if len(b) < headerSize {
+		return nil, ErrShortMessage
+	}
+
+	m := &Message{Type: b[0]}
+
+	var opts []byte
+	if m.IsRelay() {
+		if len(b) < relayHeaderSize {
+			return nil, ErrShortMessage
+		}
+		m.HopCount = b[1]
+		m.LinkAddress = net.IP(append([]byte{}, b[2:18]...))
+		m.PeerAddress = net.IP(append([]byte{}, b[18:34]...))
+		opts = b[relayHeaderSize:]
+	} else {
+		m.TransactionID = uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+		opts = b[headerSize:]
+	}
+
+	var err error
+	m.Options, err = DecodeOptions(opts)
+	return m, err
+}
+
+// Inner returns the message relayed in a relay agent message.
+func (m *Message) Inner() (*Message, error) {
+	if !m.IsRelay() {
+		return nil, ErrNotRelay
+	}
+	b, ok := m.Options.Get(RelayMessage)
+	if !ok {
+		return nil, ErrInvalidOption
+	}
+	return Decode(b)
+}
+
+// Innermost returns the client/server message carried in a chain of relay
+// messages, or the message itself if it isn't relayed.
+func (m *Message) Innermost() (*Message, error) {
+	var err error
+	for i := 0; m.IsRelay(); i++ {
+		if i > 32 {
+			return nil, ErrInvalidOption
+		}
+		if m, err = m.Inner(); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// NewRelayForw encapsulates a message received from a peer in a
+// Relay-forward message.
+func NewRelayForw(inner *Message, link, peer net.IP) *Message {
+	m := &Message{
+		Type:        RelayForw,
+		LinkAddress: link,
+		PeerAddress: peer,
+	}
+	if inner.IsRelay() {
+		m.HopCount = inner.HopCount + 1
+	}
+	m.Options.Add(RelayMessage, inner.Encode())
+	return m
+}
+
+// ClientID returns the client DUID, if present.
+func (m *Message) ClientID() DUID {
+	b, _ := m.Options.Get(ClientID)
+	return DUID(b)
+}
+
+// ServerID returns the server DUID, if present.
+func (m *Message) ServerID() DUID {
+	b, _ := m.Options.Get(ServerID)
+	return DUID(b)
+}
@@ -0,0 +1,79 @@
+package dhcp6
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestSolicitEncodeDecode(t *testing.T) {
+	mac := net.HardwareAddr{1, 2, 3, 4, 5, 6}
+	m := NewSolicit(mac)
+
+	b := m.Encode()
+	if b[0] != Solicit {
+		t.Fatalf("expect type %d, got %d", Solicit, b[0])
+	}
+
+	x, err := Decode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x.Type != Solicit || x.TransactionID != m.TransactionID {
+		t.Fatalf("expect %v, got %v", m, x)
+	}
+	if hw := x.ClientID().HardwareAddr(); !bytes.Equal(hw, mac) {
+		t.Fatalf("expect client MAC %s, got %s", mac, hw)
+	}
+
+	data, ok := x.Options.Get(IANAOption)
+	if !ok {
+		t.Fatal("IA_NA option not found")
+	}
+	ia, err := ParseIANA(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ia.IAID != [4]byte{3, 4, 5, 6} {
+		t.Fatalf("unexpected IAID %v", ia.IAID)
+	}
+}
+
+func TestRelayMessage(t *testing.T) {
+	inner := NewSolicit(net.HardwareAddr{1, 2, 3, 4, 5, 6})
+	link := net.ParseIP("2001:db8::1")
+	peer := net.ParseIP("fe80::1")
+
+	r := NewRelayForw(NewRelayForw(inner, link, peer), link, peer)
+	if r.HopCount != 1 {
+		t.Fatalf("expect hop count 1, got %d", r.HopCount)
+	}
+
+	x, err := Decode(r.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !x.LinkAddress.Equal(link) || !x.PeerAddress.Equal(peer) {
+		t.Fatalf("unexpected relay addresses %s, %s", x.LinkAddress, x.PeerAddress)
+	}
+
+	m, err := x.Innermost()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Type != Solicit || m.TransactionID != inner.TransactionID {
+		t.Fatalf("expect %v, got %v", inner, m)
+	}
+}
+
+func TestDecodeShort(t *testing.T) {
+	if _, err := Decode([]byte{Solicit, 0}); err != ErrShortMessage {
+		t.Fatalf("expect %v, got %v", ErrShortMessage, err)
+	}
+	if _, err := Decode([]byte{RelayForw, 0, 0, 0}); err != ErrShortMessage {
+		t.Fatalf("expect %v, got %v", ErrShortMessage, err)
+	}
+	if _, err := Decode([]byte{Advertise, 0, 0, 1, 0, 1, 0, 5, 0}); err != ErrInvalidOption {
+		t.Fatalf("expect %v, got %v", ErrInvalidOption, err)
+	}
+}
@@ -0,0 +1,110 @@
+package dhcp6
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+const (
+	ClientPort = 546
+	ServerPort = 547
+)
+
+// AllServers is the All_DHCP_Relay_Agents_and_Servers multicast address.
+var AllServers = net.ParseIP("ff02::1:2")
+
+// Conn sends and receives DHCPv6 messages on a network interface.
+type Conn struct {
+	conn       *net.UDPConn
+	iface      *net.Interface
+	remotePort int
+}
+
+// NewClient returns a connection receiving messages addressed to the
+// client port on the named interface.
+func NewClient(name string) (*Conn, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp6", &net.UDPAddr{
+		IP:   net.IPv6unspecified,
+		Port: ClientPort,
+		Zone: iface.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{conn, iface, ServerPort}, nil
+}
+
+// NewServer returns a connection receiving messages multicast to servers
+// and relay agents on the named interface.
+func NewServer(name string) (*Conn, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenMulticastUDP("udp6", iface, &net.UDPAddr{
+		IP:   AllServers,
+		Port: ServerPort,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{conn, iface, ClientPort}, nil
+}
+
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// Multicast sends a message to all servers and relay agents on the link.
+func (c *Conn) Multicast(ctx context.Context, m *Message) error {
+	return c.Send(ctx, m, &net.UDPAddr{
+		IP:   AllServers,
+		Port: c.remotePort,
+		Zone: c.iface.Name,
+	})
+}
+
+// Send sends a message to the given address.
+func (c *Conn) Send(ctx context.Context, m *Message, addr *net.UDPAddr) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	c.conn.SetWriteDeadline(deadline)
+
+	_, err := c.conn.WriteToUDP(m.Encode(), addr)
+	return err
+}
+
+// Receive waits for a message. A malformed message is returned with the
+// decoding error and the address of the sender.
+func (c *Conn) Receive(ctx context.Context) (*Message, *net.UDPAddr, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	// unblock the read when the context is done
+	deadline, _ := ctx.Deadline()
+	c.conn.SetReadDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() {
+		c.conn.SetReadDeadline(time.Now())
+	})
+	defer stop()
+
+	b := make([]byte, maxMessageSize)
+	n, remote, err := c.conn.ReadFromUDP(b)
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return nil, nil, err
+	}
+
+	m, err := Decode(b[:n])
+	return m, remote, err
+}
@@ -0,0 +1,460 @@
+package dhcp6
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// Option codes
+const (
+	ClientID               = 1
+	ServerID               = 2
+	IANAOption             = 3
+	IATAOption             = 4
+	IAAddrOption           = 5
+	OptionRequest          = 6
+	Preference             = 7
+	ElapsedTime            = 8
+	RelayMessage           = 9
+	Authentication         = 11
+	ServerUnicast          = 12
+	StatusCodeOption       = 13
+	RapidCommit            = 14
+	UserClass              = 15
+	VendorClass            = 16
+	VendorOpts             = 17
+	InterfaceID            = 18
+	ReconfigureMessage     = 19
+	ReconfigureAccept      = 20
+	DNSServers             = 23
+	DomainList             = 24
+	IAPDOption             = 25
+	IAPrefixOption         = 26
+	InformationRefreshTime = 32
+	SolMaxRT               = 82
+)
+
+var optionNames = map[uint16]string{
+	ClientID:               "Client Identifier",
+	ServerID:               "Server Identifier",
+	IANAOption:             "IA_NA",
+	IATAOption:             "IA_TA",
+	IAAddrOption:           "IA Address",
+	OptionRequest:          "Option Request",
+	Preference:             "Preference",
+	ElapsedTime:            "Elapsed Time",
+	RelayMessage:           "Relay Message",
+	Authentication:         "Authentication",
+	ServerUnicast:          "Server Unicast",
+	StatusCodeOption:       "Status Code",
+	RapidCommit:            "Rapid Commit",
+	UserClass:              "User Class",
+	VendorClass:            "Vendor Class",
+	VendorOpts:             "Vendor Options",
+	InterfaceID:            "Interface-Id",
+	ReconfigureMessage:     "Reconfigure Message",
+	ReconfigureAccept:      "Reconfigure Accept",
+	DNSServers:             "DNS Servers",
+	DomainList:             "Domain Search List",
+	IAPDOption:             "IA_PD",
+	IAPrefixOption:         "IA Prefix",
+	InformationRefreshTime: "Information Refresh Time",
+	SolMaxRT:               "SOL_MAX_RT",
+}
+
+// OptionName returns the name of the option code.
+func OptionName(code uint16) string {
+	if s, ok := optionNames[code]; ok {
+		return s
+	}
+	return fmt.Sprintf("Option %d", code)
+}
+
+var ErrInvalidOption = errors.New("dhcp6: invalid option")
+
+// Option is a DHCPv6 option.
+type Option struct {
+	Code uint16
+	Data []byte
+}
+
+// Options is a list of options in the order they appear in a message.
+type Options []Option
+
+// DecodeOptions parses a sequence of options.
+func DecodeOptions(b []byte) (Options, error) {
+	var opts Options
+	for len(b) > 0 {
+		if len(b) < 4 {
+			return opts, ErrInvalidOption
+		}
+		code := binary.BigEndian.Uint16(b)
+		size := int(binary.BigEndian.Uint16(b[2:]))
+		if len(b) < 4+size {
+			return opts, ErrInvalidOption
+		}
+		opts = append(opts, Option{code, append([]byte{}, b[4:4+size]...)})
+		b = b[4+size:]
+	}
+	return opts, nil
+}
+
+// Encode returns the wire format of the options.
+func (opts Options) Encode() []byte {
+	var b []byte
+	for _, o := range opts {
+		b = binary.BigEndian.AppendUint16(b, o.Code)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(o.Data)))
+		b = append(b, o.Data...)
+	}
+	return b
+}
+
+// Get returns the data of the first option with the given code, and
+// whether it was found.
+func (opts Options) Get(code uint16) ([]byte, bool) {
+	for _, o := range opts {
+		if o.Code == code {
+			return o.Data, true
+		}
+	}
+	return nil, false
+}
+
+// Add appends an option.
+func (opts *Options) Add(code uint16, data []byte) {
+	*opts = append(*opts, Option{code, data})
+}
+
+// DUID types
+const (
+	DUIDLLT  = 1
+	DUIDEN   = 2
+	DUIDLL   = 3
+	DUIDUUID = 4
+)
+
+// DUID is a DHCP Unique Identifier.
+type DUID []byte
+
+// DUIDFromMAC returns a link-layer address DUID for an Ethernet address.
+func DUIDFromMAC(mac net.HardwareAddr) DUID {
+	return append(DUID{0, DUIDLL, 0, 1}, mac...)
+}
+
+// Type returns the DUID type, or zero if the DUID is invalid.
+func (d DUID) Type() uint16 {
+	if len(d) < 2 {
+		return 0
+	}
+	return binary.BigEndian.Uint16(d)
+}
+
+// HardwareAddr returns the link-layer address in a DUID-LLT or DUID-LL,
+// or nil for other types.
+func (d DUID) HardwareAddr() net.HardwareAddr {
+	switch d.Type() {
+	case DUIDLLT:
+		if len(d) > 8 {
+			return net.HardwareAddr(d[8:])
+		}
+	case DUIDLL:
+		if len(d) > 4 {
+			return net.HardwareAddr(d[4:])
+		}
+	}
+	return nil
+}
+
+func (d DUID) String() string {
+	if len(d) == 0 {
+		return ""
+	}
+	hex := make([]string, len(d))
+	for i, x := range d {
+		hex[i] = fmt.Sprintf("%02x", x)
+	}
+	s := strings.Join(hex, ":")
+	switch d.Type() {
+	case DUIDLLT:
+		return "DUID-LLT " + s
+	case DUIDEN:
+		return "DUID-EN " + s
+	case DUIDLL:
+		return "DUID-LL " + s
+	case DUIDUUID:
+		return "DUID-UUID " + s
+	}
+	return s
+}
+
+// IANA is an Identity Association for Non-temporary Addresses, also used
+// for prefix delegation (IA_PD), which has the same layout.
+type IANA struct {
+	IAID    [4]byte
+	T1      time.Duration
+	T2      time.Duration
+	Options Options
+}
+
+// IAPD is an Identity Association for Prefix Delegation.
+type IAPD = IANA
+
+// ParseIANA parses IA_NA or IA_PD option data.
+func ParseIANA(b []byte) (*IANA, error) {
+	if len(b) < 12 {
+		return nil, ErrInvalidOption
+	}
+	ia := &IANA{
+		T1: seconds(b[4:]),
+		T2: seconds(b[8:]),
+	}
+	copy(ia.IAID[:], b)
+	var err error
+	ia.Options, err = DecodeOptions(b[12:])
+	return ia, err
+}
+
+// Encode returns the option data of the identity association.
+func (ia *IANA) Encode() []byte {
+	b := make([]byte, 12)
+	copy(b, ia.IAID[:])
+	binary.BigEndian.PutUint32(b[4:], uint32(ia.T1/time.Second))
+	binary.BigEndian.PutUint32(b[8:], uint32(ia.T2/time.Second))
+	return append(b, ia.Options.Encode()...)
+}
+
+// Addresses returns the addresses in the identity association.
+func (ia *IANA) Addresses() []*IAAddress {
+	var list []*IAAddress
+	for _, o := range ia.Options {
+		if o.Code != IAAddrOption {
+			continue
+		}
+		if a, err := ParseIAAddress(o.Data); err == nil {
+			list = append(list, a)
+		}
+	}
+	return list
+}
+
+// Prefixes returns the prefixes in the identity association.
+func (ia *IANA) Prefixes() []*IAPrefix {
+	var list []*IAPrefix
+	for _, o := range ia.Options {
+		if o.Code != IAPrefixOption {
+			continue
+		}
+		if p, err := ParseIAPrefix(o.Data); err == nil {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
+// Status returns the status code in the identity association, if any.
+func (ia *IANA) Status() *StatusCode {
+	if b, ok := ia.Options.Get(StatusCodeOption); ok {
+		s, _ := ParseStatusCode(b)
+		return s
+	}
+	return nil
+}
+
+// IAAddress is an address assigned in an identity association.
+type IAAddress struct {
+	IP                net.IP
+	PreferredLifetime time.Duration
+	ValidLifetime     time.Duration
+	Options           Options
+}
+
+// ParseIAAddress parses IA Address option data.
+func ParseIAAddress(b []byte) (*IAAddress, error) {
+	if len(b) < 24 {
+		return nil, ErrInvalidOption
+	}
+	a := &IAAddress{
+		IP:                net.IP(append([]byte{}, b[:16]...)),
+		PreferredLifetime: seconds(b[16:]),
+		ValidLifetime:     seconds(b[20:]),
+	}
+	var err error
+	a.Options, err = DecodeOptions(b[24:])
+	return a, err
+}
+
+func (a *IAAddress) String() string {
+	return fmt.Sprintf("%s (preferred %s, valid %s)", a.IP,
+		a.PreferredLifetime, a.ValidLifetime)
+}
+
+// IAPrefix is a prefix delegated in an identity association.
+type IAPrefix struct {
+	Prefix            *net.IPNet
+	PreferredLifetime time.Duration
+	ValidLifetime     time.Duration
+	Options           Options
+}
+
+// ParseIAPrefix parses IA Prefix option data.
+func ParseIAPrefix(b []byte) (*IAPrefix, error) {
+	if len(b) < 25 || b[8] > 128 {
+		return nil, ErrInvalidOption
+	}
+	p := &IAPrefix{
+		PreferredLifetime: seconds(b[0:]),
+		ValidLifetime:     seconds(b[4:]),
+		Prefix: &net.IPNet{
+			IP:   net.IP(append([]byte{}, b[9:25]...)),
+			Mask: net.CIDRMask(int(b[8]), 128),
+		},
+	}
+	var err error
+	p.Options, err = DecodeOptions(b[25:])
+	return p, err
+}
+
+func (p *IAPrefix) String() string {
+	return fmt.Sprintf("%s (preferred %s, valid %s)", p.Prefix,
+		p.PreferredLifetime, p.ValidLifetime)
+}
+
+// Status codes
+const (
+	StatusSuccess       = 0
+	StatusUnspecFail    = 1
+	StatusNoAddrsAvail  = 2
+	StatusNoBinding     = 3
+	StatusNotOnLink     = 4
+	StatusUseMulticast  = 5
+	StatusNoPrefixAvail = 6
+)
+
+var statusNames = map[uint16]string{
+	StatusSuccess:       "Success",
+	StatusUnspecFail:    "UnspecFail",
+	StatusNoAddrsAvail:  "NoAddrsAvail",
+	StatusNoBinding:     "NoBinding",
+	StatusNotOnLink:     "NotOnLink",
+	StatusUseMulticast:  "UseMulticast",
+	StatusNoPrefixAvail: "NoPrefixAvail",
+}
+
+// StatusCode is the status of an operation.
+type StatusCode struct {
+	Code    uint16
+	Message string
+}
+
+// ParseStatusCode parses Status Code option data.
+func ParseStatusCode(b []byte) (*StatusCode, error) {
+	if len(b) < 2 {
+		return nil, ErrInvalidOption
+	}
+	return &StatusCode{binary.BigEndian.Uint16(b), string(b[2:])}, nil
+}
+
+func (s *StatusCode) String() string {
+	name, ok := statusNames[s.Code]
+	if !ok {
+		name = fmt.Sprintf("Status %d", s.Code)
+	}
+	if s.Message == "" {
+		return name
+	}
+	return fmt.Sprintf("%s (%q)", name, s.Message)
+}
+
+// ParseIPs parses a list of IPv6 addresses, such as the DNS Recursive
+// Name Server option.
+func ParseIPs(b []byte) ([]net.IP, error) {
+	if len(b)%16 != 0 {
+		return nil, ErrInvalidOption
+	}
+	var list []net.IP
+	for i := 0; i < len(b); i += 16 {
+		list = append(list, net.IP(append([]byte{}, b[i:i+16]...)))
+	}
+	return list, nil
+}
+
+// EncodeIPs returns the option data of a list of IPv6 addresses.
+func EncodeIPs(list []net.IP) []byte {
+	var b []byte
+	for _, ip := range list {
+		b = append(b, ip.To16()...)
+	}
+	return b
+}
+
+// ParseDomainList parses a list of uncompressed domain names in DNS
+// wire format (RFC 8415 section 10).
+func ParseDomainList(b []byte) ([]string, error) {
+	var list []string
+	var labels []string
+	for len(b) > 0 {
+		n := int(b[0])
+		if n == 0 {
+			list = append(list, strings.Join(labels, "."))
+			labels = nil
+			b = b[1:]
+			continue
+		}
+		if n > 63 || len(b) < 1+n {
+			return nil, ErrInvalidOption
+		}
+		labels = append(labels, string(b[1:1+n]))
+		b = b[1+n:]
+	}
+	if labels != nil {
+		return nil, ErrInvalidOption
+	}
+	return list, nil
+}
+
+// EncodeDomainList returns the option data of a list of domain names.
+func EncodeDomainList(list []string) ([]byte, error) {
+	var b []byte
+	for _, name := range list {
+		for _, l := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+			if len(l) == 0 || len(l) > 63 {
+				return nil, ErrInvalidOption
+			}
+			b = append(b, byte(len(l)))
+			b = append(b, l...)
+		}
+		b = append(b, 0)
+	}
+	return b, nil
+}
+
+// ParseOptionRequest parses the list of option codes in an Option Request
+// option.
+func ParseOptionRequest(b []byte) ([]uint16, error) {
+	if len(b)%2 != 0 {
+		return nil, ErrInvalidOption
+	}
+	var list []uint16
+	for i := 0; i < len(b); i += 2 {
+		list = append(list, binary.BigEndian.Uint16(b[i:]))
+	}
+	return list, nil
+}
+
+// EncodeOptionRequest returns the data of an Option Request option.
+func EncodeOptionRequest(codes ...uint16) []byte {
+	var b []byte
+	for _, c := range codes {
+		b = binary.BigEndian.AppendUint16(b, c)
+	}
+	return b
+}
+
+func seconds(b []byte) time.Duration {
+	return time.Duration(binary.BigEndian.Uint32(b)) * time.Second
+}
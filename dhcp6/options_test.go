@@ -0,0 +1,90 @@
+package dhcp6
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestIANAAddresses(t *testing.T) {
+	addr := make([]byte, 24)
+	copy(addr, net.ParseIP("2001:db8::100"))
+	addr[19] = 100 // preferred lifetime
+	addr[23] = 200 // valid lifetime
+
+	ia := &IANA{IAID: [4]byte{1, 2, 3, 4}, T1: time.Minute, T2: 2 * time.Minute}
+	ia.Options.Add(IAAddrOption, addr)
+	ia.Options.Add(StatusCodeOption, append([]byte{0, StatusSuccess}, "ok"...))
+
+	x, err := ParseIANA(ia.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x.T1 != time.Minute || x.T2 != 2*time.Minute {
+		t.Fatalf("unexpected T1 %s, T2 %s", x.T1, x.T2)
+	}
+
+	list := x.Addresses()
+	if len(list) != 1 || list[0].String() != "2001:db8::100 (preferred 1m40s, valid 3m20s)" {
+		t.Fatalf("unexpected addresses %v", list)
+	}
+	if s := x.Status(); s == nil || s.String() != `Success ("ok")` {
+		t.Fatalf("unexpected status %v", s)
+	}
+}
+
+func TestIAPrefix(t *testing.T) {
+	b := make([]byte, 25)
+	b[3] = 60
+	b[7] = 120
+	b[8] = 56
+	copy(b[9:], net.ParseIP("2001:db8:1200::"))
+
+	p, err := ParseIAPrefix(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.String() != "2001:db8:1200::/56 (preferred 1m0s, valid 2m0s)" {
+		t.Fatalf("unexpected prefix %s", p)
+	}
+
+	b[8] = 129
+	if _, err := ParseIAPrefix(b); err != ErrInvalidOption {
+		t.Fatalf("expect %v, got %v", ErrInvalidOption, err)
+	}
+}
+
+func TestDomainList(t *testing.T) {
+	list := []string{"example.com", "lab.example.org"}
+	b, err := EncodeDomainList(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := ParseDomainList(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(x) != 2 || x[0] != list[0] || x[1] != list[1] {
+		t.Fatalf("expect %v, got %v", list, x)
+	}
+
+	// compression is not allowed
+	if _, err := ParseDomainList([]byte{3, 'f', 'o', 'o', 0xc0, 0}); err != ErrInvalidOption {
+		t.Fatalf("expect %v, got %v", ErrInvalidOption, err)
+	}
+}
+
+func TestDUID(t *testing.T) {
+	d := DUIDFromMAC(net.HardwareAddr{1, 2, 3, 4, 5, 6})
+	if d.String() != "DUID-LL 00:03:00:01:01:02:03:04:05:06" {
+		t.Fatalf("unexpected DUID %s", d)
+	}
+
+	llt := DUID{0, 1, 0, 1, 0x2a, 0x2b, 0x2c, 0x2d, 1, 2, 3, 4, 5, 6}
+	if llt.HardwareAddr().String() != "01:02:03:04:05:06" {
+		t.Fatalf("unexpected hardware address %s", llt.HardwareAddr())
+	}
+	if (DUID{0, DUIDEN, 0, 0, 0, 9}).HardwareAddr() != nil {
+		t.Fatal("unexpected hardware address in DUID-EN")
+	}
+}
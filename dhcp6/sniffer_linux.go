@@ -0,0 +1,121 @@
+package dhcp6
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+)
+
+// pollInterval is how often a blocked Receive checks its context.
+const pollInterval = 200 * time.Millisecond
+
+// Sniffer captures the DHCPv6 messages seen on a network interface, sent
+// to either the client or the server port, through a packet socket.
+type Sniffer struct {
+	fd      int
+	lastMAC net.HardwareAddr
+}
+
+func htons(x uint16) uint16 {
+	return x<<8 | x>>8
+}
+
+// NewSniffer returns a sniffer on the named interface, which is put in
+// promiscuous mode to see messages addressed to other hosts.
+func NewSniffer(name string) (*Sniffer, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	proto := htons(syscall.ETH_P_IPV6)
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, int(proto))
+	if err != nil {
+		return nil, fmt.Errorf("dhcp6: packet socket: %s", err)
+	}
+
+	err = syscall.Bind(fd, &syscall.SockaddrLinklayer{
+		Protocol: proto,
+		Ifindex:  iface.Index,
+	})
+	if err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("dhcp6: bind to %s: %s", name, err)
+	}
+
+	// struct packet_mreq
+	mreq := make([]byte, 16)
+	binary.NativeEndian.PutUint32(mreq[0:], uint32(iface.Index))
+	binary.NativeEndian.PutUint16(mreq[4:], syscall.PACKET_MR_PROMISC)
+	err = syscall.SetsockoptString(fd, syscall.SOL_PACKET,
+		syscall.PACKET_ADD_MEMBERSHIP, string(mreq))
+	if err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("dhcp6: promiscuous mode: %s", err)
+	}
+
+	return &Sniffer{fd: fd}, nil
+}
+
+func (s *Sniffer) Close() error {
+	return syscall.Close(s.fd)
+}
+
+// Receive waits for a message. A malformed message is returned with the
+// decoding error and the address of the sender.
+func (s *Sniffer) Receive(ctx context.Context) (*Message, *net.UDPAddr, error) {
+	b := make([]byte, maxMessageSize+etherHeaderSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		// wake up periodically to check the context
+		wait := pollInterval
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			wait = time.Until(deadline)
+			if wait <= 0 {
+				return nil, nil, context.DeadlineExceeded
+			}
+		}
+		tv := syscall.NsecToTimeval(wait.Nanoseconds())
+		err := syscall.SetsockoptTimeval(s.fd, syscall.SOL_SOCKET,
+			syscall.SO_RCVTIMEO, &tv)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		n, from, err := syscall.Recvfrom(s.fd, b, 0)
+		switch err {
+		case nil:
+		case syscall.EAGAIN, syscall.EINTR:
+			continue
+		default:
+			return nil, nil, err
+		}
+
+		// skip our own packets
+		if ll, ok := from.(*syscall.SockaddrLinklayer); ok &&
+			ll.Pkttype == syscall.PACKET_OUTGOING {
+			continue
+		}
+
+		f, err := decodeFrame(b[:n])
+		if err != nil || !f.isDHCP() {
+			continue
+		}
+
+		s.lastMAC = f.srcMAC
+		m, err := Decode(f.payload)
+		return m, &net.UDPAddr{IP: f.srcIP, Port: f.srcPort}, err
+	}
+}
+
+// RemoteMAC returns the hardware address of the sender of the last
+// message received.
+func (s *Sniffer) RemoteMAC() net.HardwareAddr {
+	return s.lastMAC
+}
@@ -0,0 +1,31 @@
+//go:build !linux
+// +build !linux
+
+package dhcp6
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+var errSnifferUnsupported = errors.New("dhcp6: packet capture not supported on this platform")
+
+// Sniffer is only available on Linux.
+type Sniffer struct{}
+
+func NewSniffer(name string) (*Sniffer, error) {
+	return nil, errSnifferUnsupported
+}
+
+func (s *Sniffer) Close() error {
+	return errSnifferUnsupported
+}
+
+func (s *Sniffer) Receive(ctx context.Context) (*Message, *net.UDPAddr, error) {
+	return nil, nil, errSnifferUnsupported
+}
+
+func (s *Sniffer) RemoteMAC() net.HardwareAddr {
+	return nil
+}
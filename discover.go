@@ -2,22 +2,44 @@ package main
 
 import (
 	"./dhcp"
+	"./dhcp6"
+	"context"
 	"flag"
 	"fmt"
+	"net"
 	"os"
 	"time"
 )
 
 var Version = "0.1"
 
-func cmdDiscover() {
+// Relay agent information added to discover packets
+var (
+	relayAddr net.IP
+	relayInfo *dhcp.RelayAgentInfo
+)
+
+// Use a raw socket bound to the interface instead of UDP sockets
+var useRaw bool
+
+func cmdDiscover(ctx context.Context) {
 	var iface string
 	var secs int
 	var sendOnly bool
+	var giaddr, circuit, remote string
+	var allow, file string
+	var v6 bool
 
 	flag.StringVar(&iface, "i", "", "network `interface` to use")
 	flag.IntVar(&secs, "t", 5, "timeout in seconds")
 	flag.BoolVar(&sendOnly, "s", false, "send discovery only and ignore offers")
+	flag.StringVar(&giaddr, "g", "", "relay agent IP `address`")
+	flag.StringVar(&circuit, "circuit", "", "relay agent circuit `ID`")
+	flag.StringVar(&remote, "remote", "", "relay agent remote `ID`")
+	flag.BoolVar(&useRaw, "R", false, "use raw socket bound to the interface")
+	flag.StringVar(&allow, "a", "", "comma-separated list of allowed server IP or MAC `addresses`")
+	flag.StringVar(&file, "w", "", "write packets to pcapng `file`")
+	flag.BoolVar(&v6, "6", false, "send DHCPv6 solicit")
 	flag.Parse()
 
 	if iface == "" {
@@ -25,85 +47,265 @@ func cmdDiscover() {
 		os.Exit(1)
 	}
 
+	if allow != "" {
+		var err error
+		allowList, err = parseAllowList(allow)
+		checkError(err)
+	}
+
+	if giaddr != "" {
+		relayAddr = net.ParseIP(giaddr).To4()
+		if relayAddr == nil {
+			checkError(fmt.Errorf("%s: invalid relay address", giaddr))
+		}
+	}
+
+	if circuit != "" || remote != "" {
+		relayInfo = &dhcp.RelayAgentInfo{}
+		if circuit != "" {
+			relayInfo.CircuitID = []byte(circuit)
+		}
+		if remote != "" {
+			relayInfo.RemoteID = []byte(remote)
+		}
+	}
+
 	timeout := time.Duration(secs) * time.Second
 	if sendOnly {
 		timeout = 0
 	}
 
-	setupSummary()
+	if v6 {
+		if useRaw || relayAddr != nil || relayInfo != nil {
+			fmt.Fprintln(os.Stderr, "raw sockets and relay options are IPv4 only")
+			usage(os.Args[1])
+			os.Exit(1)
+		}
+		if file != "" {
+			checkError(fmt.Errorf("DHCPv6 packets can't be written to file"))
+		}
+		_, err := discover6(ctx, iface, timeout, false)
+		checkError(err)
+		return
+	}
+
+	openCapture(file)
+	defer closeCapture()
 
-	discover(iface, timeout, false)
+	_, err := discover(ctx, iface, timeout, false)
+	checkError(err)
+}
+
+type offer struct {
+	ip  string
+	mac string
 }
 
-func discover(iface string, timeout time.Duration, silent bool) {
+// discover broadcasts a discover packet on the interface and returns the
+// offers received before the timeout. If silent is set, nothing is shown.
+func discover(ctx context.Context, iface string, timeout time.Duration, silent bool) ([]offer, error) {
 
 	mac, err := MACFromIface(iface)
-	checkError(err)
+	if err != nil {
+		return nil, err
+	}
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return nil, err
+	}
 
-	fmt.Printf("Interface: %s [%s]\n", iface, mac)
+	if !silent {
+		fmt.Printf("Interface: %s [%s]\n", iface, mac)
+	}
 
-	var client *dhcp.Client
+	var offers []offer
 
-	if timeout <= 0 {
-		client, err = dhcp.NewClientNotListening()
-		checkError(err)
-	} else {
-		client, err = dhcp.NewClient()
-		checkError(err)
-		defer client.Close()
+	_, err = dhcp.Discover(ctx, iface, &dhcp.DiscoverOptions{
+		Timeout:     timeout,
+		ClientMAC:   hw,
+		VendorClass: "dhcpcheck-" + Version,
+		RelayAddr:   relayAddr,
+		RelayInfo:   relayInfo,
+		Raw:         useRaw,
+		SendOnly:    timeout <= 0,
+
+		Sent: func(p *dhcp.Packet) {
+			if silent {
+				rememberRequest(p)
+			} else {
+				fmt.Println("\n>>> Send DHCP discover")
+				showPacket(p, "")
+			}
+			record(time.Now(), p, "0.0.0.0", mac, "", "", nil)
+
+			stats.pksent++
+			stats.count[mac]++
+		},
+
+		Received: func(o *dhcp.Offer) {
+			rip := o.ServerIP.String()
+			rmac := MACFromIP(rip)
+			if o.ServerMAC != nil {
+				rmac = o.ServerMAC.String()
+			}
+
+			stats.pkrec++
+			stats.pkproc++
+			stats.count[rmac]++
+
+			var notes []string
+			if !silent {
+				fmt.Printf("\n<<< Receive DHCP offer from %s (%s)\n",
+					rip, NameFromIP(rip))
+				fmt.Printf("    MAC address: %s (%s)\n",
+					rmac, VendorFromMAC(rmac))
+
+				warnings := showPacket(o.Packet, rip)
+				notes = verdict(o.Packet, rip, rmac, warnings)
+			}
+			record(time.Now(), o.Packet, rip, rmac, "", mac, notes)
+
+			offers = append(offers, offer{rip, rmac})
+		},
+	})
+	if err == context.Canceled {
+		// interrupted, show what was received
+		err = nil
+	}
+	if err != nil {
+		return offers, err
 	}
 
-	// Send discover packet
-	p := dhcp.NewDiscoverPacket()
-	p.SetClientMAC(mac)
-	class := "dhcpcheck-" + Version
-	p.AddOptions(append(
-		[]byte{dhcp.VendorClassIdentifier, byte(len(class))},
-		[]byte(class)...))
+	if !silent && timeout > 0 {
+		fmt.Println("No more offers.")
+	}
+
+	return offers, nil
+}
+
+// senderMAC6 returns the hardware address of the client or server sending
+// a DHCPv6 message, as found in its DUID.
+func senderMAC6(m *dhcp6.Message) string {
+	var d dhcp6.DUID
+	switch m.Type {
+	case dhcp6.Advertise, dhcp6.Reply, dhcp6.Reconfigure:
+		d = m.ServerID()
+	case dhcp6.RelayForw, dhcp6.RelayRepl:
+		// sent by a relay agent
+		return ""
+	default:
+		d = m.ClientID()
+	}
+	if hw := d.HardwareAddr(); hw != nil {
+		return hw.String()
+	}
+	return ""
+}
+
+func discover6(ctx context.Context, iface string, timeout time.Duration, silent bool) ([]offer, error) {
 
-	fmt.Println("\n>>> Send DHCP discover")
+	mac, err := MACFromIface(iface)
+	if err != nil {
+		return nil, err
+	}
 
 	if !silent {
-		showPacket(p, "")
+		fmt.Printf("Interface: %s [%s]\n", iface, mac)
+	}
+
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := dhcp6.NewClient(iface)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	// Send solicit message
+	m := dhcp6.NewSolicit(hw)
+	m.Options.Add(dhcp6.VendorClass, vendorClassData6("dhcpcheck-"+Version))
+
+	if !silent {
+		fmt.Println("\n>>> Send DHCPv6 solicit")
+		showMessage6(m, "")
+	}
+	if err := client.Multicast(ctx, m); err != nil {
+		return nil, err
 	}
-	err = client.Broadcast(p)
-	checkError(err)
 
 	stats.pksent++
 	stats.count[mac]++
 
 	if timeout <= 0 {
-		return
+		return nil, nil
 	}
 
-	t := time.Now()
-	for time.Since(t) < timeout {
-		o, remote, err := client.Receive(timeout)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+	var offers []offer
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		a, remote, err := client.Receive(ctx)
+		if err == context.DeadlineExceeded || err == context.Canceled {
 			break
 		}
+		if err != nil {
+			if remote == nil {
+				return offers, err
+			}
+			if !silent {
+				fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			}
+			continue
+		}
 
 		stats.pkrec++
 
-		cmac := o.Chaddr.MACAddress().String()
-
-		if mac != cmac || o.Xid != p.Xid {
+		if a.Type != dhcp6.Advertise || a.TransactionID != m.TransactionID {
 			continue
 		}
 
 		rip := remote.IP.String()
-		rmac := MACFromIP(rip)
+		rmac := senderMAC6(a)
 
 		stats.pkproc++
-		stats.count[rmac]++
+		if rmac != "" {
+			stats.count[rmac]++
+		}
 
-		fmt.Printf("\n<<< Receive DHCP offer from %s (%s)\n",
-			rip, NameFromIP(rip))
-		fmt.Printf("    MAC address: %s (%s)\n",
-			rmac, VendorFromMAC(rmac))
+		if !silent {
+			fmt.Printf("\n<<< Receive DHCPv6 advertise from %s (%s)\n",
+				rip, NameFromIP(rip))
+			if rmac != "" {
+				fmt.Printf("    MAC address: %s (%s)\n",
+					rmac, VendorFromMAC(rmac))
+			}
 
-		showPacket(&o, rip)
+			showMessage6(a, rip)
+		}
+		if len(allowList) > 0 && !isAllowed(allowList, offer{rip, rmac}) {
+			if !silent {
+				fmt.Println("Warning: server not in allow list")
+			}
+			stats.warn["rogue server"]++
+		}
+
+		offers = append(offers, offer{rip, rmac})
 	}
-	fmt.Println("No more offers.")
+	if !silent {
+		fmt.Println("No more offers.")
+	}
+
+	return offers, nil
+}
+
+// vendorClassData6 returns Vendor Class option data with no enterprise
+// number.
+func vendorClassData6(s string) []byte {
+	b := []byte{0, 0, 0, 0, byte(len(s) >> 8), byte(len(s))}
+	return append(b, s...)
 }
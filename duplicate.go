@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"./dhcp"
+)
+
+// replySubnet returns the subnet of the address assigned in a reply, or
+// nil if the reply has no subnet mask.
+func replySubnet(p *dhcp.Packet) *net.IPNet {
+	b, ok := p.Option(dhcp.SubnetMask)
+	if !ok || len(b) != 4 {
+		return nil
+	}
+	mask := net.IPMask(b)
+	return &net.IPNet{IP: p.Yiaddr.IP().Mask(mask), Mask: mask}
+}
+
+// check looks for conflicts between the address assigned in an offer or
+// acknowledgement and the leases and assignments of other servers, and
+// records the address as assigned by the server sending an ACK.
+func (lt *leaseTable) check(p *dhcp.Packet, server string, now time.Time) []string {
+	ip := p.Yiaddr.IP()
+	if ip.Equal(net.IPv4zero) {
+		return nil
+	}
+	mac := p.Chaddr.MACAddress().String()
+	ack := p.MessageType() == dhcp.DHCPAck
+
+	var warnings []string
+
+	// Address leased to another client
+	if l := lt.ip[ip.String()]; l != nil && l.MAC != mac && l.valid(now) {
+		var w string
+		if ack {
+			w = fmt.Sprintf("duplicate address %s assigned to %s by %s, leased to %s by %s",
+				ip, mac, server, l.MAC, l.Server)
+			stats.warn["duplicate address"]++
+		} else {
+			w = fmt.Sprintf("address %s offered to %s by %s, leased to %s by %s",
+				ip, mac, server, l.MAC, l.Server)
+			stats.warn["offer of leased address"]++
+		}
+		warnings = append(warnings, w)
+		lt.conflict(w)
+	}
+
+	if !ack {
+		return warnings
+	}
+
+	// Address also assigned by another server. Pools are only known from
+	// the addresses actually assigned, so servers sharing a subnet with
+	// disjoint pools aren't reported.
+	servers := lt.assigned[ip.String()]
+	if servers == nil {
+		servers = map[string]bool{}
+		lt.assigned[ip.String()] = servers
+	}
+	for other := range servers {
+		if other == server {
+			continue
+		}
+		w := fmt.Sprintf("address %s assigned by %s was also assigned by %s",
+			ip, server, other)
+		stats.warn["pool overlap"]++
+		warnings = append(warnings, w)
+		lt.conflict(w)
+	}
+	servers[server] = true
+
+	return warnings
+}
+
+// conflict adds a conflict to the report, if not already there.
+func (lt *leaseTable) conflict(s string) {
+	for _, c := range lt.conflicts {
+		if c == s {
+			return
+		}
+	}
+	lt.conflicts = append(lt.conflicts, s)
+	report.Conflicts = lt.conflicts
+}
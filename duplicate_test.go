@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"./dhcp"
+)
+
+// ackPacket returns a DHCPACK from a server assigning an address.
+func ackPacket(server, mac string, ip byte) *dhcp.Packet {
+	p := dhcp.NewDiscoverPacket()
+	p.Op = dhcp.BootReply
+	p.SetClientMAC(mac)
+	p.SetOption(dhcp.DHCPMessageType, []byte{dhcp.DHCPAck})
+	p.SetIP(dhcp.ServerIdentifier, net.ParseIP(server))
+	p.SetOption(dhcp.IPAddressLeaseTime, []byte{0, 0, 0x0e, 0x10})
+	copy(p.Yiaddr[:], []byte{10, 0, 0, ip})
+	return p
+}
+
+func TestInterleavedAssignments(t *testing.T) {
+	leases = leaseTable{
+		ip:       map[string]*Lease{},
+		host:     map[string]string{},
+		assigned: map[string]map[string]bool{},
+	}
+	now := time.Now()
+
+	// two servers sharing a subnet with interleaved addresses
+	for i := byte(10); i < 20; i++ {
+		server := "10.0.0.1"
+		if i%2 == 1 {
+			server = "10.0.0.2"
+		}
+		mac := fmt.Sprintf("00:11:22:33:44:%02x", i)
+		if w := leases.track(ackPacket(server, mac, i), server, now); len(w) > 0 {
+			t.Fatalf("unexpected warnings %v", w)
+		}
+	}
+
+	// an address assigned by both servers overlaps
+	w := leases.track(ackPacket("10.0.0.2", "00:11:22:33:44:0a", 10), "10.0.0.2", now)
+	if len(w) != 1 {
+		t.Fatalf("expect 1 warning, got %v", w)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"net"
+	"time"
+
+	"./dhcp"
+)
+
+// floodConfig holds the thresholds of the flood detectors, as the number
+// of events in the window. Zero disables a detector.
+type floodConfig struct {
+	window   time.Duration
+	clients  int // distinct clients sending discovers
+	requests int // discovers and requests from a single client
+	naks     int // NAKs from all servers
+	noOffer  int // discovers with no offer
+}
+
+// window counts events in a sliding time window.
+type window struct {
+	size   time.Duration
+	events []time.Time
+	alarm  bool // threshold exceeded
+}
+
+// add adds an event and returns the number of events in the window.
+func (w *window) add(t time.Time) int {
+	w.events = append(w.events, t)
+	return w.expire(t)
+}
+
+// expire removes events older than the window size.
+func (w *window) expire(now time.Time) int {
+	i := 0
+	for i < len(w.events) && now.Sub(w.events[i]) > w.size {
+		i++
+	}
+	w.events = w.events[i:]
+	return len(w.events)
+}
+
+// exceeded reports whether the count crossed the threshold upwards. The
+// alarm is rearmed when the count falls below half the threshold.
+func (w *window) exceeded(n, threshold int) bool {
+	switch {
+	case threshold <= 0:
+		return false
+	case n >= threshold && !w.alarm:
+		w.alarm = true
+		return true
+	case n < threshold/2:
+		w.alarm = false
+	}
+	return false
+}
+
+// sighting is a client seen in the flood window.
+type sighting struct {
+	mac    string
+	time   time.Time
+	random bool // locally administered or unknown vendor address
+}
+
+// floodDetector recognizes starvation attacks, request storms and pool
+// exhaustion from the rate of snooped packets.
+type floodDetector struct {
+	config     floodConfig
+	clients    []sighting         // clients sending discovers, by first discover
+	discover   map[string]bool    // clients in the queue above
+	random     int                // random clients in the queue above
+	requests   []sighting         // discovers and requests, in arrival order
+	perClient  map[string]*window // map client MAC to discovers and requests
+	clientWin  window
+	nakWin     window
+	noOfferWin window
+}
+
+var floods = floodDetector{
+	discover:  map[string]bool{},
+	perClient: map[string]*window{},
+}
+
+// configure sets the detector thresholds.
+func (fd *floodDetector) configure(c floodConfig) {
+	fd.config = c
+	fd.clientWin.size = c.window
+	fd.nakWin.size = c.window
+	fd.noOfferWin.size = c.window
+}
+
+// track adds a packet seen at the given time to the detectors.
+func (fd *floodDetector) track(p *dhcp.Packet, now time.Time) {
+	mac := p.Chaddr.MACAddress().String()
+
+	switch p.MessageType() {
+	case dhcp.DHCPDiscover:
+		fd.starvation(mac, now)
+		fd.storm(mac, "discover", now)
+
+	case dhcp.DHCPRequest:
+		fd.storm(mac, "request", now)
+
+	case dhcp.DHCPNack:
+		if fd.nakWin.exceeded(fd.nakWin.add(now), fd.config.naks) {
+			stats.warn["NAK storm"]++
+			alert("NAK storm: %d NAKs in %s", len(fd.nakWin.events),
+				fd.config.window)
+		}
+	}
+}
+
+// starvation counts the distinct clients sending discovers in the window.
+// Starvation tools usually make up random addresses, so the clients with
+// locally administered or unknown vendor addresses are also shown.
+func (fd *floodDetector) starvation(mac string, now time.Time) {
+	// forget clients first seen before the window
+	i := 0
+	for ; i < len(fd.clients) && now.Sub(fd.clients[i].time) > fd.config.window; i++ {
+		c := fd.clients[i]
+		delete(fd.discover, c.mac)
+		if c.random {
+			fd.random--
+		}
+	}
+	fd.clients = fd.clients[i:]
+
+	if !fd.discover[mac] {
+		c := sighting{mac: mac, time: now}
+		hw, err := net.ParseMAC(mac)
+		if err == nil && hw[0]&0x02 != 0 {
+			c.random = true
+		} else if v, _ := db.Lookup(mac); v == "" {
+			c.random = true
+		}
+		if c.random {
+			fd.random++
+		}
+		fd.clients = append(fd.clients, c)
+		fd.discover[mac] = true
+	}
+
+	n := len(fd.clients)
+	if fd.clientWin.exceeded(n, fd.config.clients) {
+		stats.warn["DHCP starvation"]++
+		alert("possible DHCP starvation: discovers from %d clients in %s (%d random or unknown vendor)",
+			n, fd.config.window, fd.random)
+	}
+}
+
+// storm counts the discovers and requests sent by a client.
+func (fd *floodDetector) storm(mac, kind string, now time.Time) {
+	// forget clients idle for the whole window
+	i := 0
+	for ; i < len(fd.requests) && now.Sub(fd.requests[i].time) > fd.config.window; i++ {
+		m := fd.requests[i].mac
+		if w := fd.perClient[m]; w != nil && w.expire(now) == 0 {
+			delete(fd.perClient, m)
+		}
+	}
+	fd.requests = append(fd.requests[i:], sighting{mac: mac, time: now})
+
+	w := fd.perClient[mac]
+	if w == nil {
+		w = &window{size: fd.config.window}
+		fd.perClient[mac] = w
+	}
+
+	if w.exceeded(w.add(now), fd.config.requests) {
+		stats.warn["request storm"]++
+		alert("%s storm from %s (%s): %d discovers and requests in %s",
+			kind, mac, VendorFromMAC(mac), len(w.events), fd.config.window)
+	}
+}
+
+// unanswered counts a discover with no offer, at the time the transaction
+// tracker found it unanswered.
+func (fd *floodDetector) unanswered(t time.Time) {
+	if fd.noOfferWin.exceeded(fd.noOfferWin.add(t), fd.config.noOffer) {
+		stats.warn["pool exhaustion"]++
+		alert("possible pool exhaustion: %d discovers with no offer in %s",
+			len(fd.noOfferWin.events), fd.config.window)
+	}
+}
@@ -85,6 +85,17 @@ func String(b []byte) string {
 	return fmt.Sprintf("%q", string(b))
 }
 
+// Printable returns opaque data as a quoted string if all characters are
+// printable ASCII, or as colon-separated hex bytes otherwise.
+func Printable(b []byte) string {
+	for _, c := range b {
+		if c < 0x20 || c > 0x7e {
+			return MACAddrString(b)
+		}
+	}
+	return String(b)
+}
+
 // Types according to RFC 1700
 func RFC1700Types(b []byte) string {
 	switch b[0] {
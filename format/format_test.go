@@ -90,3 +90,15 @@ func TestMACAddressString(t *testing.T) {
 	s := MACAddressString(b)
 	checkResult(t, b, s, "01:02:03:04:05:ff")
 }
+
+func TestPrintable1(t *testing.T) {
+	b := []byte("eth0/1")
+	s := Printable(b)
+	checkResult(t, b, s, "\"eth0/1\"")
+}
+
+func TestPrintable2(t *testing.T) {
+	b := []byte{0, 4, 0, 10, 1, 2}
+	s := Printable(b)
+	checkResult(t, b, s, "00:04:00:0a:01:02")
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"./dhcp"
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+func cmdLease(ctx context.Context) {
+	var iface string
+	var server string
+	var secs int
+	var release bool
+
+	flag.StringVar(&iface, "i", "", "network `interface` to use")
+	flag.StringVar(&server, "s", "", "accept offer from server `address` only")
+	flag.IntVar(&secs, "t", 5, "timeout in seconds")
+	flag.BoolVar(&release, "r", false, "release the lease when done")
+	flag.Parse()
+
+	if iface == "" {
+		usage(os.Args[1])
+		os.Exit(1)
+	}
+
+	var sip net.IP
+	if server != "" {
+		sip = net.ParseIP(server).To4()
+		if sip == nil {
+			checkError(fmt.Errorf("%s: invalid server address", server))
+		}
+	}
+
+	lease(ctx, iface, sip, time.Duration(secs)*time.Second, release)
+}
+
+// waitReply waits for a reply of the given message types matching the
+// request. If server is not nil, only replies from that server are
+// accepted.
+func waitReply(ctx context.Context, client *dhcp.Client, req *dhcp.Packet,
+	server net.IP, timeout time.Duration, types ...byte) (*dhcp.Packet, string, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		o, remote, err := client.Receive(ctx)
+		if err == context.DeadlineExceeded {
+			return nil, "", fmt.Errorf("no reply after %s", timeout)
+		}
+		if err != nil {
+			return nil, "", err
+		}
+
+		stats.pkrec++
+
+		if o.Xid != req.Xid || o.Chaddr != req.Chaddr {
+			continue
+		}
+
+		if server != nil && !remote.IP.Equal(server) &&
+			!server.Equal(o.ServerID()) {
+			continue
+		}
+
+		for _, mt := range types {
+			if o.MessageType() == mt {
+				return &o, remote.IP.String(), nil
+			}
+		}
+	}
+}
+
+func showReply(p *dhcp.Packet, rip string, elapsed time.Duration) {
+	rmac := MACFromIP(rip)
+
+	stats.pkproc++
+	stats.count[rmac]++
+
+	fmt.Printf("\n<<< Receive %s from %s (%s) in %s\n",
+		messageName(p.MessageType()), rip, NameFromIP(rip), elapsed)
+	fmt.Printf("    MAC address: %s (%s)\n", rmac, VendorFromMAC(rmac))
+
+	showPacket(p, rip)
+}
+
+func lease(ctx context.Context, iface string, server net.IP, timeout time.Duration, release bool) {
+
+	mac, err := MACFromIface(iface)
+	checkError(err)
+
+	fmt.Printf("Interface: %s [%s]\n", iface, mac)
+
+	client, err := dhcp.NewClient()
+	checkError(err)
+	defer client.Close()
+
+	// Discover
+	p := dhcp.NewDiscoverPacket()
+	p.SetClientMAC(mac)
+	p.SetString(dhcp.VendorClassIdentifier, "dhcpcheck-"+Version)
+
+	fmt.Println("\n>>> Send DHCP discover")
+	showPacket(p, "")
+
+	t0 := time.Now()
+	err = client.Broadcast(ctx, p)
+	checkError(err)
+	stats.pksent++
+	stats.count[mac]++
+
+	// Offer
+	o, rip, err := waitReply(ctx, client, p, server, timeout, dhcp.DHCPOffer)
+	if ctx.Err() != nil {
+		return
+	}
+	checkError(err)
+	t1 := time.Now()
+	showReply(o, rip, t1.Sub(t0))
+
+	// Request
+	r := dhcp.NewRequestPacket(o)
+	r.SetString(dhcp.VendorClassIdentifier, "dhcpcheck-"+Version)
+
+	fmt.Println("\n>>> Send DHCP request")
+	showPacket(r, "")
+
+	t2 := time.Now()
+	err = client.Broadcast(ctx, r)
+	checkError(err)
+	stats.pksent++
+	stats.count[mac]++
+
+	// Ack
+	a, rip, err := waitReply(ctx, client, r, o.ServerID(), timeout,
+		dhcp.DHCPAck, dhcp.DHCPNack)
+	if ctx.Err() != nil {
+		return
+	}
+	checkError(err)
+	t3 := time.Now()
+	showReply(a, rip, t3.Sub(t2))
+
+	fmt.Println("\nTiming")
+	fmt.Println("  Discover -> Offer :", t1.Sub(t0))
+	fmt.Println("  Offer -> Request  :", t2.Sub(t1))
+	fmt.Println("  Request -> Reply  :", t3.Sub(t2))
+	fmt.Println("  Total             :", t3.Sub(t0))
+
+	if a.MessageType() != dhcp.DHCPAck {
+		fmt.Println("\nRequest not acknowledged.")
+		return
+	}
+
+	fmt.Printf("\nLeased %s for %s.\n", a.Yiaddr.String(), a.LeaseTime())
+
+	if !release {
+		return
+	}
+
+	// Release
+	sid := a.ServerID()
+	if sid == nil {
+		sid = net.ParseIP(rip)
+	}
+	rel := dhcp.NewReleasePacket(a)
+
+	fmt.Printf("\n>>> Send DHCP release to %s\n", sid)
+	showPacket(rel, "")
+
+	// release even if interrupted
+	err = client.SendTo(context.WithoutCancel(ctx), rel, sid)
+	checkError(err)
+	stats.pksent++
+	stats.count[mac]++
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"./dhcp"
+)
+
+// Lease states
+const (
+	leaseActive   = "active"
+	leaseReleased = "released"
+	leaseDeclined = "declined"
+)
+
+// Lease is an address assignment seen in a DHCPACK.
+type Lease struct {
+	MAC       string
+	IP        string
+	Hostname  string
+	LeaseTime uint32    // seconds
+	Expiry    time.Time // zero if infinite
+	Server    string
+	Relay     string
+	State     string
+}
+
+func (l *Lease) expiryString() string {
+	if l.Expiry.IsZero() {
+		return "never"
+	}
+	return l.Expiry.Format(timeFormat)
+}
+
+// valid reports whether the lease is in use at the given time.
+func (l *Lease) valid(now time.Time) bool {
+	return l.State == leaseActive && (l.Expiry.IsZero() || now.Before(l.Expiry))
+}
+
+// leaseTable is the set of leases reconstructed from snooped packets.
+type leaseTable struct {
+	ip        map[string]*Lease          // map IP address to lease
+	host      map[string]string          // map client MAC to host name in requests
+	assigned  map[string]map[string]bool // map IP address to servers assigning it
+	conflicts []string
+}
+
+var leases = leaseTable{
+	ip:       map[string]*Lease{},
+	host:     map[string]string{},
+	assigned: map[string]map[string]bool{},
+}
+
+// track updates the lease table with a packet seen at the given time, and
+// returns warnings about conflicting address assignments.
+func (lt *leaseTable) track(p *dhcp.Packet, originIP string, now time.Time) []string {
+	mac := p.Chaddr.MACAddress().String()
+
+	server := serverKey(p, originIP)
+
+	switch p.MessageType() {
+	case dhcp.DHCPDiscover, dhcp.DHCPRequest:
+		// servers don't always echo the host name
+		if b, ok := p.Option(dhcp.HostName); ok {
+			lt.host[mac] = cString(b)
+		}
+
+	case dhcp.DHCPOffer:
+		return lt.check(p, server, now)
+
+	case dhcp.DHCPAck:
+		ip := p.Yiaddr.IP()
+		if ip.Equal(net.IPv4zero) {
+			// reply to DHCPINFORM
+			return nil
+		}
+		warnings := lt.check(p, server, now)
+
+		l := &Lease{
+			MAC:      mac,
+			IP:       ip.String(),
+			Hostname: lt.host[mac],
+			Server:   server,
+			State:    leaseActive,
+		}
+		if b, ok := p.Option(dhcp.HostName); ok {
+			l.Hostname = cString(b)
+		}
+		if !p.Giaddr.IP().Equal(net.IPv4zero) {
+			l.Relay = p.Giaddr.IP().String()
+		}
+		if b, ok := p.Option(dhcp.IPAddressLeaseTime); ok && len(b) == 4 {
+			l.LeaseTime = binary.BigEndian.Uint32(b)
+			if l.LeaseTime != 0xffffffff {
+				l.Expiry = now.Add(p.LeaseTime())
+			}
+		}
+		lt.ip[l.IP] = l
+		report.Leases = lt.list()
+		return warnings
+
+	case dhcp.DHCPRelease:
+		lt.end(p.Ciaddr.IP().String(), mac, leaseReleased)
+
+	case dhcp.DHCPDecline:
+		if b, ok := p.Option(dhcp.RequestedIPAddress); ok && len(b) == 4 {
+			lt.end(net.IP(b).String(), mac, leaseDeclined)
+		}
+	}
+
+	return nil
+}
+
+// end changes the state of the lease of an address held by the client.
+func (lt *leaseTable) end(ip, mac, state string) {
+	if l := lt.ip[ip]; l != nil && l.MAC == mac {
+		l.State = state
+		report.Leases = lt.list()
+	}
+}
+
+// list returns the leases sorted by IP address.
+func (lt *leaseTable) list() []*Lease {
+	var list []*Lease
+	for _, l := range lt.ip {
+		list = append(list, l)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := net.ParseIP(list[i].IP).To4(), net.ParseIP(list[j].IP).To4()
+		return string(a) < string(b)
+	})
+	return list
+}
+
+// summary shows the lease table and the address conflicts found.
+func (lt *leaseTable) summary() {
+	if len(lt.conflicts) > 0 {
+		fmt.Println("\nAddress conflicts")
+		for _, c := range lt.conflicts {
+			fmt.Println(" ", c)
+		}
+	}
+
+	if len(lt.ip) == 0 {
+		return
+	}
+
+	fmt.Println("\nLeases")
+	fmt.Printf("  %-15s %-17s %-8s %-26s %-15s %-15s %s\n", "IP address",
+		"MAC address", "State", "Expires", "Server", "Relay", "Host name")
+	for _, l := range lt.list() {
+		fmt.Printf("  %-15s %-17s %-8s %-26s %-15s %-15s %s\n", l.IP,
+			l.MAC, l.State, l.expiryString(), l.Server, l.Relay,
+			l.Hostname)
+	}
+}
+
+// export writes the lease table to a file, in JSON format if the file
+// name ends in .json and CSV otherwise.
+func (lt *leaseTable) export(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if strings.ToLower(filepath.Ext(name)) == ".json" {
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(lt.list()); err != nil {
+			return err
+		}
+		return f.Close()
+	}
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"mac", "ip", "hostname", "lease_time", "expiry",
+		"server", "relay", "state"})
+	for _, l := range lt.list() {
+		var expiry string
+		if !l.Expiry.IsZero() {
+			expiry = l.Expiry.Format(time.RFC3339)
+		}
+		w.Write([]string{l.MAC, l.IP, l.Hostname,
+			strconv.FormatUint(uint64(l.LeaseTime), 10), expiry,
+			l.Server, l.Relay, l.State})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
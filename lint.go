@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"./dhcp"
+)
+
+// Interface MTUs outside this range are reported as implausible.
+const (
+	minMTU = 576
+	maxMTU = 9216
+)
+
+// lint looks for configuration errors in offers and acknowledgements,
+// such as addresses outside the client subnet and inconsistent lease
+// timers.
+func lint(p *dhcp.Packet, originIP string) []string {
+	t := p.MessageType()
+	if p.Op != dhcp.BootReply || (t != dhcp.DHCPOffer && t != dhcp.DHCPAck) {
+		return nil
+	}
+
+	var warnings []string
+	warn := func(kind, format string, a ...interface{}) {
+		stats.warn[kind]++
+		warnings = append(warnings, fmt.Sprintf(format, a...))
+	}
+
+	// Server identifier, except in replies through a relay agent
+	id := p.ServerID()
+	giaddr := p.Giaddr.IP()
+	relayed := !giaddr.Equal(net.IPv4zero)
+	switch {
+	case id == nil:
+		warn("missing server identifier", "missing server identifier")
+	case !relayed && originIP != "" && originIP != "0.0.0.0" && !id.Equal(net.ParseIP(originIP)):
+		warn("server identifier mismatch",
+			"server identifier %s doesn't match source %s", id, originIP)
+	}
+
+	// Lease timers
+	lease := p.LeaseTime()
+	t1 := durationOption(p, dhcp.RenewalTimeValue)
+	t2 := durationOption(p, dhcp.RebindingTimeValue)
+	if t1 > 0 && t2 > 0 && t1 >= t2 {
+		warn("inconsistent lease timers", "T1 (%d) not less than T2 (%d)", t1, t2)
+	}
+	secs := uint32(lease.Seconds())
+	switch {
+	case lease == 0:
+		// no lease time to compare with
+	case t2 > 0 && t2 >= secs:
+		warn("inconsistent lease timers", "T2 (%d) not less than lease time (%d)",
+			t2, secs)
+	case t1 > 0 && t1 >= secs:
+		warn("inconsistent lease timers", "T1 (%d) not less than lease time (%d)",
+			t1, secs)
+	}
+
+	// Interface MTU
+	if b, ok := p.Option(dhcp.InterfaceMTU); ok && len(b) == 2 {
+		if mtu := binary.BigEndian.Uint16(b); mtu < minMTU || mtu > maxMTU {
+			warn("implausible MTU", "implausible interface MTU %d", mtu)
+		}
+	}
+
+	// Addresses in the client subnet
+	client := p.Yiaddr.IP()
+	if client.Equal(net.IPv4zero) {
+		// reply to DHCPINFORM
+		client = p.Ciaddr.IP()
+	}
+	subnet := replySubnet(p)
+	if subnet == nil || client.Equal(net.IPv4zero) {
+		return warnings
+	}
+	ones, bits := subnet.Mask.Size()
+	if bits == 0 {
+		warn("invalid subnet mask", "invalid subnet mask %s", net.IP(subnet.Mask))
+		return warnings
+	}
+
+	// The client subnet is the subnet of the relay agent or, for servers
+	// on the link, of the server.
+	var ref net.IP
+	var refName string
+	switch {
+	case relayed:
+		ref, refName = giaddr, "relay"
+	case originIP != "" && originIP != "0.0.0.0":
+		ref, refName = net.ParseIP(originIP).To4(), "server"
+	case id != nil:
+		ref, refName = id.To4(), "server"
+	}
+	if ref != nil {
+		subnet.IP = ref.Mask(subnet.Mask)
+		if !subnet.Contains(client) {
+			warn("address outside subnet",
+				"client address %s outside subnet %s of %s %s", client,
+				subnet, refName, ref)
+		}
+	} else {
+		subnet.IP = client.Mask(subnet.Mask)
+	}
+	network, broadcast := subnetBounds(subnet)
+
+	// point-to-point links have no network and broadcast addresses
+	special := func(ip net.IP) bool {
+		return ones < 31 && (ip.Equal(network) || ip.Equal(broadcast))
+	}
+
+	if special(client) {
+		warn("network or broadcast address",
+			"client address %s is the network or broadcast address of %s",
+			client, subnet)
+	}
+
+	if routers, ok := p.Option(dhcp.Router); ok {
+		for i := 0; i+4 <= len(routers); i += 4 {
+			r := net.IP(routers[i : i+4])
+			if !subnet.Contains(r) {
+				warn("router outside subnet", "router %s outside subnet %s", r, subnet)
+			}
+		}
+	}
+
+	if b, ok := p.Option(dhcp.BroadcastAddress); ok && len(b) == 4 {
+		if bc := net.IP(b); !bc.Equal(broadcast) {
+			warn("inconsistent broadcast address",
+				"broadcast address %s doesn't match subnet %s", bc, subnet)
+		}
+	}
+
+	if dns, ok := p.Option(dhcp.DomainNameServer); ok {
+		for i := 0; i+4 <= len(dns); i += 4 {
+			s := net.IP(dns[i : i+4])
+			if special(s) || s.Equal(net.IPv4zero) || s.Equal(net.IPv4bcast) {
+				warn("invalid DNS server",
+					"DNS server %s is a network or broadcast address", s)
+			}
+		}
+	}
+
+	return warnings
+}
+
+// durationOption returns the value of a time option in seconds, or zero
+// if not present.
+func durationOption(p *dhcp.Packet, code byte) uint32 {
+	if b, ok := p.Option(code); ok && len(b) == 4 {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+// subnetBounds returns the network and broadcast addresses of a subnet.
+func subnetBounds(n *net.IPNet) (net.IP, net.IP) {
+	network := n.IP.To4().Mask(n.Mask)
+	broadcast := make(net.IP, 4)
+	for i := range broadcast {
+		broadcast[i] = network[i] | ^n.Mask[i]
+	}
+	return network, broadcast
+}
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
 	"sort"
 	"strings"
+	"syscall"
 )
 
 var (
@@ -14,14 +16,17 @@ var (
 	report StatReport
 	repch  chan string
 
-	cmd map[string]func()
+	cmd map[string]func(context.Context)
 )
 
 type ServerStats struct {
-	Name  string
-	Offer uint
-	Ack   uint
-	Nack  uint
+	Name   string
+	Offer  uint
+	Ack    uint
+	Nack   uint
+	Advert uint    // router advertisements
+	Impl   string  // likely server implementation
+	Conf   float64 // confidence of the implementation guess
 }
 
 type Statistics struct {
@@ -32,14 +37,22 @@ type Statistics struct {
 	msg    map[string]uint        // map msg type to count
 	vdc    map[string]uint        // map vendor class to count
 	srv    map[string]ServerStats // map servers to count
+	relay  map[string]uint        // map relay circuit to count
+	warn   map[string]uint        // map anomaly type to count
+	dev    map[string]string      // map client MAC to device class
 }
 
 type StatReport struct {
-	Packets int
-	MsgType map[string]uint
-	Vendors map[string]uint
-	VdClass map[string]uint
-	Servers map[string]ServerStats
+	Packets   int
+	MsgType   map[string]uint
+	Vendors   map[string]uint
+	VdClass   map[string]uint
+	Servers   map[string]ServerStats
+	Devices   map[string]uint   // map device class to client count
+	Transact  map[string]uint   // map transaction outcome to count
+	Failed    map[string]string // map client MAC to failed outcome
+	Leases    []*Lease
+	Conflicts []string
 }
 
 func init() {
@@ -49,15 +62,21 @@ func init() {
 		msg:   map[string]uint{},
 		vdc:   map[string]uint{},
 		srv:   map[string]ServerStats{},
+		relay: map[string]uint{},
+		warn:  map[string]uint{},
+		dev:   map[string]string{},
 	}
 
 	report = StatReport{
 		MsgType: map[string]uint{},
 	}
 
-	cmd = map[string]func(){
+	cmd = map[string]func(context.Context){
 		"discover": cmdDiscover,
 		"snoop":    cmdSnoop,
+		"sentry":   cmdSentry,
+		"lease":    cmdLease,
+		"ra":       cmdRA,
 	}
 
 	repch = make(chan string, 10)
@@ -70,16 +89,6 @@ func checkError(err error) {
 	}
 }
 
-func setupSummary() {
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-	go func() {
-		<-c
-		summary()
-		os.Exit(1)
-	}()
-}
-
 func summary() {
 	fmt.Println("\nPacket summary")
 	fmt.Println("  Packets sent      :", stats.pksent)
@@ -109,6 +118,41 @@ func summary() {
 			fmt.Printf("  %-20.20s : %d\n", key, val)
 		}
 	}
+
+	if len(stats.srv) > 0 {
+		fmt.Println("\nServers")
+		for key, val := range stats.srv {
+			impl := "unknown"
+			if val.Impl != "" {
+				impl = fmt.Sprintf("%s (%.0f%%)", val.Impl, val.Conf*100)
+			}
+			fmt.Printf("  %-15.15s : %-24.24s %s\n", key, impl, val.Name)
+		}
+	}
+
+	if len(stats.dev) > 0 {
+		fmt.Println("\nClient devices")
+		for key, val := range deviceCount() {
+			fmt.Printf("  %-30.30s : %d\n", key, val)
+		}
+	}
+
+	if len(stats.warn) > 0 {
+		fmt.Println("\nWarnings")
+		for key, val := range stats.warn {
+			fmt.Printf("  %-30.30s : %d\n", key, val)
+		}
+	}
+
+	if len(stats.relay) > 0 {
+		fmt.Println("\nRelay circuits")
+		for key, val := range stats.relay {
+			fmt.Printf("  %-40.40s : %d\n", key, val)
+		}
+	}
+
+	transactions.summary()
+	leases.summary()
 }
 
 func usage(c string) {
@@ -144,8 +188,22 @@ func main() {
 		if len(os.Args) > 2 {
 			os.Args = append(os.Args[:1], os.Args[2:]...)
 		}
-		handle()
+
+		// Commands return when interrupted, so output is flushed and
+		// files are closed before the summary is shown
+		ctx, stop := signal.NotifyContext(context.Background(),
+			os.Interrupt, syscall.SIGTERM)
+		go func() {
+			// a second signal terminates immediately
+			<-ctx.Done()
+			stop()
+		}()
+
+		handle(ctx)
 		summary()
+		if ctx.Err() != nil {
+			os.Exit(1)
+		}
 		os.Exit(0)
 	}
 
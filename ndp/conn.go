@@ -0,0 +1,111 @@
+package ndp
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// AllRouters is the all-routers link-local multicast address.
+var AllRouters = net.ParseIP("ff02::2")
+
+// Conn receives router advertisements on a network interface.
+type Conn struct {
+	conn  *net.IPConn
+	iface *net.Interface
+}
+
+// Listen returns a connection receiving ICMPv6 messages on the named
+// interface. Raw sockets need CAP_NET_RAW.
+func Listen(name string) (*Conn, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenIP("ip6:ipv6-icmp", &net.IPAddr{
+		IP:   net.IPv6unspecified,
+		Zone: iface.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// neighbor discovery messages must be sent with hop limit 255
+	if err := setHopLimit(conn, 255); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// the zone doesn't bind the socket to the interface, so packets are
+	// filtered by the interface they arrived on
+	if err := setReceiveInfo(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Conn{conn, iface}, nil
+}
+
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// Solicit sends a router solicitation to all routers on the link, so they
+// advertise without waiting for the next periodic advertisement.
+func (c *Conn) Solicit(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b := make([]byte, 8)
+	b[0] = TypeRouterSolicitation
+	if c.iface.HardwareAddr != nil {
+		b = appendOption(b, OptionSourceLinkAddr, c.iface.HardwareAddr)
+	}
+
+	deadline, _ := ctx.Deadline()
+	c.conn.SetWriteDeadline(deadline)
+
+	_, err := c.conn.WriteToIP(b, &net.IPAddr{IP: AllRouters, Zone: c.iface.Name})
+	return err
+}
+
+// Receive waits for a router advertisement. Other ICMPv6 messages, and
+// messages arriving on other interfaces or forwarded by a router (hop
+// limit other than 255, RFC 4861 section 6.1.2), are ignored. A malformed
+// advertisement is returned with the decoding error and the address of
+// the sender.
+func (c *Conn) Receive(ctx context.Context) (*RouterAdvertisement, *net.IPAddr, error) {
+	// unblock the read when the context is done
+	deadline, _ := ctx.Deadline()
+	c.conn.SetReadDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() {
+		c.conn.SetReadDeadline(time.Now())
+	})
+	defer stop()
+
+	b := make([]byte, 65535)
+	oob := make([]byte, 128)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		n, oobn, _, remote, err := c.conn.ReadMsgIP(b, oob)
+		if err != nil {
+			if ctx.Err() != nil {
+				err = ctx.Err()
+			}
+			return nil, nil, err
+		}
+
+		if n == 0 || b[0] != TypeRouterAdvertisement {
+			continue
+		}
+
+		ifindex, hops := receiveInfo(oob[:oobn])
+		if (ifindex != 0 && ifindex != c.iface.Index) || (hops >= 0 && hops != 255) {
+			continue
+		}
+
+		ra, err := ParseRouterAdvertisement(b[:n])
+		return ra, remote, err
+	}
+}
@@ -0,0 +1,337 @@
+// Package ndp decodes IPv6 Neighbor Discovery router advertisements
+// (RFC 4861) and sends router solicitations.
+package ndp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// ICMPv6 message types
+const (
+	TypeRouterSolicitation  = 133
+	TypeRouterAdvertisement = 134
+)
+
+// Option types
+const (
+	OptionSourceLinkAddr = 1
+	OptionPrefixInfo     = 3
+	OptionMTU            = 5
+	OptionRouteInfo      = 24
+	OptionRDNSS          = 25
+	OptionDNSSL          = 31
+)
+
+// Router preferences (RFC 4191)
+const (
+	PreferenceMedium = 0
+	PreferenceHigh   = 1
+	PreferenceLow    = 3
+)
+
+const raHeaderSize = 16
+
+var (
+	ErrShortMessage  = errors.New("ndp: message too short")
+	ErrNotAdvert     = errors.New("ndp: not a router advertisement")
+	ErrInvalidOption = errors.New("ndp: invalid option")
+)
+
+// PrefixInfo is an on-link or autoconfiguration prefix.
+type PrefixInfo struct {
+	Prefix            *net.IPNet
+	OnLink            bool
+	Autonomous        bool
+	ValidLifetime     time.Duration
+	PreferredLifetime time.Duration
+}
+
+func (p PrefixInfo) String() string {
+	var flags []string
+	if p.OnLink {
+		flags = append(flags, "on-link")
+	}
+	if p.Autonomous {
+		flags = append(flags, "autonomous")
+	}
+	return fmt.Sprintf("%s [%s] (preferred %s, valid %s)", p.Prefix,
+		strings.Join(flags, ","), p.PreferredLifetime, p.ValidLifetime)
+}
+
+// RouteInfo is a more specific route announced by the router.
+type RouteInfo struct {
+	Prefix     *net.IPNet
+	Preference int
+	Lifetime   time.Duration
+}
+
+func (r RouteInfo) String() string {
+	return fmt.Sprintf("%s (%s, lifetime %s)", r.Prefix,
+		PreferenceName(r.Preference), r.Lifetime)
+}
+
+// RDNSS is a list of recursive DNS servers (RFC 8106).
+type RDNSS struct {
+	Lifetime time.Duration
+	Servers  []net.IP
+}
+
+// DNSSL is a DNS search list (RFC 8106).
+type DNSSL struct {
+	Lifetime time.Duration
+	Domains  []string
+}
+
+// RouterAdvertisement is an ICMPv6 router advertisement message.
+type RouterAdvertisement struct {
+	HopLimit       byte
+	Managed        bool // addresses available with DHCPv6
+	Other          bool // other configuration available with DHCPv6
+	Preference     int
+	RouterLifetime time.Duration
+	ReachableTime  time.Duration
+	RetransTimer   time.Duration
+	SourceLinkAddr net.HardwareAddr
+	MTU            uint32
+	Prefixes       []PrefixInfo
+	Routes         []RouteInfo
+	RDNSS          []RDNSS
+	DNSSL          []DNSSL
+}
+
+// PreferenceName returns the name of a router preference value.
+func PreferenceName(p int) string {
+	switch p {
+	case PreferenceMedium:
+		return "medium"
+	case PreferenceHigh:
+		return "high"
+	case PreferenceLow:
+		return "low"
+	}
+	return "reserved"
+}
+
+// ParseRouterAdvertisement parses an ICMPv6 router advertisement, starting
+// with the ICMPv6 type.
+func ParseRouterAdvertisement(b []byte) (*RouterAdvertisement, error) {
+	if len(b) < raHeaderSize {
+		return nil, ErrShortMessage
+	}
+	if b[0] != TypeRouterAdvertisement || b[1] != 0 {
+		return nil, ErrNotAdvert
+	}
+
+	ra := &RouterAdvertisement{
+		HopLimit:       b[4],
+		Managed:        b[5]&0x80 != 0,
+		Other:          b[5]&0x40 != 0,
+		Preference:     int(b[5]>>3) & 0x03,
+		RouterLifetime: time.Duration(binary.BigEndian.Uint16(b[6:])) * time.Second,
+		ReachableTime:  time.Duration(binary.BigEndian.Uint32(b[8:])) * time.Millisecond,
+		RetransTimer:   time.Duration(binary.BigEndian.Uint32(b[12:])) * time.Millisecond,
+	}
+
+	opts := b[raHeaderSize:]
+	for len(opts) > 0 {
+		if len(opts) < 2 || opts[1] == 0 || len(opts) < int(opts[1])*8 {
+			return ra, ErrInvalidOption
+		}
+		o := opts[:int(opts[1])*8]
+		opts = opts[len(o):]
+
+		if err := ra.parseOption(o[0], o[2:]); err != nil {
+			return ra, err
+		}
+	}
+
+	return ra, nil
+}
+
+func (ra *RouterAdvertisement) parseOption(t byte, b []byte) error {
+	switch t {
+	case OptionSourceLinkAddr:
+		ra.SourceLinkAddr = net.HardwareAddr(append([]byte{}, b...))
+		if len(b) > 6 {
+			// padded Ethernet address
+			ra.SourceLinkAddr = ra.SourceLinkAddr[:6]
+		}
+
+	case OptionMTU:
+		if len(b) < 6 {
+			return ErrInvalidOption
+		}
+		ra.MTU = binary.BigEndian.Uint32(b[2:])
+
+	case OptionPrefixInfo:
+		if len(b) < 30 || b[0] > 128 {
+			return ErrInvalidOption
+		}
+		ra.Prefixes = append(ra.Prefixes, PrefixInfo{
+			Prefix:            prefix(b[14:30], int(b[0])),
+			OnLink:            b[1]&0x80 != 0,
+			Autonomous:        b[1]&0x40 != 0,
+			ValidLifetime:     seconds(b[2:]),
+			PreferredLifetime: seconds(b[6:]),
+		})
+
+	case OptionRouteInfo:
+		if len(b) < 6 || b[0] > 128 || len(b)-6 < (int(b[0])+7)/8 {
+			return ErrInvalidOption
+		}
+		ip := make([]byte, 16)
+		copy(ip, b[6:])
+		ra.Routes = append(ra.Routes, RouteInfo{
+			Prefix:     prefix(ip, int(b[0])),
+			Preference: int(b[1]>>3) & 0x03,
+			Lifetime:   seconds(b[2:]),
+		})
+
+	case OptionRDNSS:
+		if len(b) < 6 || (len(b)-6)%16 != 0 {
+			return ErrInvalidOption
+		}
+		r := RDNSS{Lifetime: seconds(b[2:])}
+		for i := 6; i < len(b); i += 16 {
+			r.Servers = append(r.Servers, net.IP(append([]byte{}, b[i:i+16]...)))
+		}
+		ra.RDNSS = append(ra.RDNSS, r)
+
+	case OptionDNSSL:
+		if len(b) < 6 {
+			return ErrInvalidOption
+		}
+		domains, err := parseDomains(b[6:])
+		if err != nil {
+			return err
+		}
+		ra.DNSSL = append(ra.DNSSL, DNSSL{seconds(b[2:]), domains})
+	}
+
+	return nil
+}
+
+// Encode returns the ICMPv6 message of the router advertisement. The
+// checksum is left for the kernel to fill in.
+func (ra *RouterAdvertisement) Encode() []byte {
+	b := make([]byte, raHeaderSize)
+	b[0] = TypeRouterAdvertisement
+	b[4] = ra.HopLimit
+	if ra.Managed {
+		b[5] |= 0x80
+	}
+	if ra.Other {
+		b[5] |= 0x40
+	}
+	b[5] |= byte(ra.Preference&0x03) << 3
+	binary.BigEndian.PutUint16(b[6:], uint16(ra.RouterLifetime/time.Second))
+	binary.BigEndian.PutUint32(b[8:], uint32(ra.ReachableTime/time.Millisecond))
+	binary.BigEndian.PutUint32(b[12:], uint32(ra.RetransTimer/time.Millisecond))
+
+	if ra.SourceLinkAddr != nil {
+		b = appendOption(b, OptionSourceLinkAddr, ra.SourceLinkAddr)
+	}
+	if ra.MTU != 0 {
+		o := make([]byte, 6)
+		binary.BigEndian.PutUint32(o[2:], ra.MTU)
+		b = appendOption(b, OptionMTU, o)
+	}
+	for _, p := range ra.Prefixes {
+		o := make([]byte, 30)
+		n, _ := p.Prefix.Mask.Size()
+		o[0] = byte(n)
+		if p.OnLink {
+			o[1] |= 0x80
+		}
+		if p.Autonomous {
+			o[1] |= 0x40
+		}
+		binary.BigEndian.PutUint32(o[2:], uint32(p.ValidLifetime/time.Second))
+		binary.BigEndian.PutUint32(o[6:], uint32(p.PreferredLifetime/time.Second))
+		copy(o[14:], p.Prefix.IP.To16())
+		b = appendOption(b, OptionPrefixInfo, o)
+	}
+	for _, r := range ra.Routes {
+		o := make([]byte, 22)
+		n, _ := r.Prefix.Mask.Size()
+		o[0] = byte(n)
+		o[1] = byte(r.Preference&0x03) << 3
+		binary.BigEndian.PutUint32(o[2:], uint32(r.Lifetime/time.Second))
+		copy(o[6:], r.Prefix.IP.To16())
+		b = appendOption(b, OptionRouteInfo, o)
+	}
+	for _, r := range ra.RDNSS {
+		o := make([]byte, 6)
+		binary.BigEndian.PutUint32(o[2:], uint32(r.Lifetime/time.Second))
+		for _, ip := range r.Servers {
+			o = append(o, ip.To16()...)
+		}
+		b = appendOption(b, OptionRDNSS, o)
+	}
+	for _, d := range ra.DNSSL {
+		o := make([]byte, 6)
+		binary.BigEndian.PutUint32(o[2:], uint32(d.Lifetime/time.Second))
+		for _, name := range d.Domains {
+			for _, l := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+				o = append(o, byte(len(l)))
+				o = append(o, l...)
+			}
+			o = append(o, 0)
+		}
+		b = appendOption(b, OptionDNSSL, o)
+	}
+
+	return b
+}
+
+// appendOption appends an option, padded to a multiple of 8 bytes.
+func appendOption(b []byte, t byte, data []byte) []byte {
+	n := (len(data) + 2 + 7) / 8
+	o := make([]byte, n*8)
+	o[0] = t
+	o[1] = byte(n)
+	copy(o[2:], data)
+	return append(b, o...)
+}
+
+// parseDomains parses uncompressed DNS names, followed by zero padding.
+func parseDomains(b []byte) ([]string, error) {
+	var list []string
+	var labels []string
+	for len(b) > 0 {
+		n := int(b[0])
+		if n == 0 {
+			if labels == nil {
+				// padding
+				break
+			}
+			list = append(list, strings.Join(labels, "."))
+			labels = nil
+			b = b[1:]
+			continue
+		}
+		if n > 63 || len(b) < 1+n {
+			return nil, ErrInvalidOption
+		}
+		labels = append(labels, string(b[1:1+n]))
+		b = b[1+n:]
+	}
+	if labels != nil {
+		return nil, ErrInvalidOption
+	}
+	return list, nil
+}
+
+func prefix(ip []byte, n int) *net.IPNet {
+	mask := net.CIDRMask(n, 128)
+	return &net.IPNet{IP: net.IP(ip).Mask(mask), Mask: mask}
+}
+
+func seconds(b []byte) time.Duration {
+	return time.Duration(binary.BigEndian.Uint32(b)) * time.Second
+}
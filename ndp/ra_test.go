@@ -0,0 +1,76 @@
+package ndp
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestRouterAdvertisement(t *testing.T) {
+	_, pfx, _ := net.ParseCIDR("2001:db8:1::/64")
+	_, route, _ := net.ParseCIDR("2001:db8:2::/48")
+
+	ra := &RouterAdvertisement{
+		HopLimit:       64,
+		Managed:        true,
+		Preference:     PreferenceHigh,
+		RouterLifetime: 30 * time.Minute,
+		SourceLinkAddr: net.HardwareAddr{1, 2, 3, 4, 5, 6},
+		MTU:            1500,
+		Prefixes: []PrefixInfo{{
+			Prefix:            pfx,
+			OnLink:            true,
+			Autonomous:        true,
+			ValidLifetime:     time.Hour,
+			PreferredLifetime: 30 * time.Minute,
+		}},
+		Routes: []RouteInfo{{route, PreferenceLow, time.Hour}},
+		RDNSS:  []RDNSS{{time.Hour, []net.IP{net.ParseIP("2001:db8::53")}}},
+		DNSSL:  []DNSSL{{time.Hour, []string{"example.com", "lab.example.com"}}},
+	}
+
+	x, err := ParseRouterAdvertisement(ra.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !x.Managed || x.Other || x.Preference != PreferenceHigh ||
+		x.HopLimit != 64 || x.RouterLifetime != 30*time.Minute || x.MTU != 1500 {
+		t.Fatalf("expect %+v, got %+v", ra, x)
+	}
+	if x.SourceLinkAddr.String() != "01:02:03:04:05:06" {
+		t.Fatalf("unexpected source link address %s", x.SourceLinkAddr)
+	}
+	if len(x.Prefixes) != 1 || x.Prefixes[0].String() !=
+		"2001:db8:1::/64 [on-link,autonomous] (preferred 30m0s, valid 1h0m0s)" {
+		t.Fatalf("unexpected prefixes %v", x.Prefixes)
+	}
+	if len(x.Routes) != 1 || x.Routes[0].String() != "2001:db8:2::/48 (low, lifetime 1h0m0s)" {
+		t.Fatalf("unexpected routes %v", x.Routes)
+	}
+	if len(x.RDNSS) != 1 || !x.RDNSS[0].Servers[0].Equal(net.ParseIP("2001:db8::53")) {
+		t.Fatalf("unexpected RDNSS %v", x.RDNSS)
+	}
+	if len(x.DNSSL) != 1 || len(x.DNSSL[0].Domains) != 2 ||
+		x.DNSSL[0].Domains[1] != "lab.example.com" {
+		t.Fatalf("unexpected DNSSL %v", x.DNSSL)
+	}
+}
+
+func TestRouterAdvertisementInvalid(t *testing.T) {
+	if _, err := ParseRouterAdvertisement([]byte{134, 0, 0, 0}); err != ErrShortMessage {
+		t.Fatalf("expect %v, got %v", ErrShortMessage, err)
+	}
+
+	b := (&RouterAdvertisement{}).Encode()
+	b[0] = TypeRouterSolicitation
+	if _, err := ParseRouterAdvertisement(b); err != ErrNotAdvert {
+		t.Fatalf("expect %v, got %v", ErrNotAdvert, err)
+	}
+
+	// zero length option
+	b = append((&RouterAdvertisement{}).Encode(), OptionMTU, 0, 0, 0, 0, 0, 0, 0)
+	if _, err := ParseRouterAdvertisement(b); err != ErrInvalidOption {
+		t.Fatalf("expect %v, got %v", ErrInvalidOption, err)
+	}
+}
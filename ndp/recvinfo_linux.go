@@ -0,0 +1,56 @@
+package ndp
+
+import (
+	"encoding/binary"
+	"net"
+	"syscall"
+)
+
+// setReceiveInfo asks for the interface and hop limit of received
+// packets as control messages.
+func setReceiveInfo(conn *net.IPConn) error {
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var serr error
+	err = rc.Control(func(fd uintptr) {
+		serr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6,
+			syscall.IPV6_RECVPKTINFO, 1)
+		if serr == nil {
+			serr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6,
+				syscall.IPV6_RECVHOPLIMIT, 1)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return serr
+}
+
+// receiveInfo returns the interface index and hop limit found in the
+// control messages of a received packet, or zero and -1 if not present.
+func receiveInfo(oob []byte) (ifindex, hops int) {
+	hops = -1
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return 0, -1
+	}
+	for _, m := range msgs {
+		if m.Header.Level != syscall.IPPROTO_IPV6 {
+			continue
+		}
+		switch int(m.Header.Type) {
+		case syscall.IPV6_PKTINFO:
+			// struct in6_pktinfo
+			if len(m.Data) >= 20 {
+				ifindex = int(binary.NativeEndian.Uint32(m.Data[16:]))
+			}
+		case syscall.IPV6_HOPLIMIT:
+			if len(m.Data) >= 4 {
+				hops = int(int32(binary.NativeEndian.Uint32(m.Data)))
+			}
+		}
+	}
+	return ifindex, hops
+}
@@ -0,0 +1,37 @@
+package ndp
+
+import (
+	"encoding/binary"
+	"syscall"
+	"testing"
+	"unsafe"
+)
+
+func controlMessage(typ int, data []byte) []byte {
+	b := make([]byte, syscall.CmsgSpace(len(data)))
+	h := (*syscall.Cmsghdr)(unsafe.Pointer(&b[0]))
+	h.Level = syscall.IPPROTO_IPV6
+	h.Type = int32(typ)
+	h.SetLen(syscall.CmsgLen(len(data)))
+	copy(b[syscall.CmsgLen(0):], data)
+	return b
+}
+
+func TestReceiveInfo(t *testing.T) {
+	pktinfo := make([]byte, 20)
+	binary.NativeEndian.PutUint32(pktinfo[16:], 3)
+	hoplimit := make([]byte, 4)
+	binary.NativeEndian.PutUint32(hoplimit, 64)
+
+	oob := append(controlMessage(syscall.IPV6_PKTINFO, pktinfo),
+		controlMessage(syscall.IPV6_HOPLIMIT, hoplimit)...)
+	ifindex, hops := receiveInfo(oob)
+	if ifindex != 3 || hops != 64 {
+		t.Fatalf("expect interface 3 and hop limit 64, got %d and %d", ifindex, hops)
+	}
+
+	ifindex, hops = receiveInfo(nil)
+	if ifindex != 0 || hops != -1 {
+		t.Fatalf("expect no interface and hop limit, got %d and %d", ifindex, hops)
+	}
+}
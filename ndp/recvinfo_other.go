@@ -0,0 +1,13 @@
+//go:build !linux
+
+package ndp
+
+import "net"
+
+func setReceiveInfo(conn *net.IPConn) error {
+	return nil
+}
+
+func receiveInfo(oob []byte) (ifindex, hops int) {
+	return 0, -1
+}
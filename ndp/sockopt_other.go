@@ -0,0 +1,9 @@
+//go:build !unix
+
+package ndp
+
+import "net"
+
+func setHopLimit(conn *net.IPConn, hops int) error {
+	return nil
+}
@@ -0,0 +1,24 @@
+//go:build unix
+
+package ndp
+
+import (
+	"net"
+	"syscall"
+)
+
+func setHopLimit(conn *net.IPConn, hops int) error {
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var serr error
+	err = rc.Control(func(fd uintptr) {
+		serr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6,
+			syscall.IPV6_MULTICAST_HOPS, hops)
+	})
+	if err != nil {
+		return err
+	}
+	return serr
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"./ndp"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+func cmdRA(ctx context.Context) {
+	var iface string
+	var allow string
+	var solicit bool
+
+	flag.StringVar(&iface, "i", "", "network `interface` to use")
+	flag.StringVar(&allow, "a", "", "comma-separated list of allowed router IP or MAC `addresses`")
+	flag.BoolVar(&solicit, "s", false, "send router solicitation")
+	flag.Parse()
+
+	if iface == "" {
+		usage(os.Args[1])
+		os.Exit(1)
+	}
+
+	if allow != "" {
+		var err error
+		allowList, err = parseAllowList(allow)
+		checkError(err)
+	}
+
+	go serve(3344)
+
+	listenRA(ctx, iface, solicit)
+}
+
+// listenRA shows router advertisements received on the interface.
+func listenRA(ctx context.Context, iface string, solicit bool) {
+	conn, err := ndp.Listen(iface)
+	checkError(err)
+	defer conn.Close()
+
+	mac, _ := MACFromIface(iface)
+	fmt.Printf("Interface: %s [%s]\n", iface, mac)
+
+	if solicit {
+		fmt.Println("\n>>> Send router solicitation")
+		err = conn.Solicit(ctx)
+		checkError(err)
+		stats.pksent++
+	}
+
+	for {
+		ra, remote, err := conn.Receive(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			if remote == nil {
+				return
+			}
+			continue
+		}
+
+		stats.pkrec++
+		stats.pkproc++
+
+		rip := remote.IP.String()
+		rmac := ""
+		if ra.SourceLinkAddr != nil {
+			rmac = ra.SourceLinkAddr.String()
+		}
+		stats.count[rmac]++
+
+		fmt.Printf("\n<<< Router advertisement from %s (%s)\n",
+			rip, NameFromIP(rip))
+		if rmac != "" {
+			fmt.Printf("    MAC address: %s (%s)\n",
+				rmac, VendorFromMAC(rmac))
+		}
+
+		showRA(ra, rip)
+
+		if len(allowList) > 0 && !isAllowed(allowList, offer{rip, rmac}) {
+			stats.warn["rogue router"]++
+			alert("advertisement from unknown router %s [%s] (%s)",
+				rip, rmac, VendorFromMAC(rmac))
+		}
+	}
+}
+
+func showRA(ra *ndp.RouterAdvertisement, originIP string) {
+	var flags []string
+	if ra.Managed {
+		flags = append(flags, "managed")
+	}
+	if ra.Other {
+		flags = append(flags, "other")
+	}
+
+	fmt.Printf("Hop limit         : %d\n", ra.HopLimit)
+	fmt.Printf("Flags             : %s\n", strings.Join(flags, ","))
+	fmt.Printf("Preference        : %s\n", ndp.PreferenceName(ra.Preference))
+	fmt.Printf("Router lifetime   : %s\n", ra.RouterLifetime)
+	if ra.ReachableTime != 0 {
+		fmt.Printf("Reachable time    : %s\n", ra.ReachableTime)
+	}
+	if ra.RetransTimer != 0 {
+		fmt.Printf("Retrans timer     : %s\n", ra.RetransTimer)
+	}
+	if ra.MTU != 0 {
+		fmt.Printf("MTU               : %d\n", ra.MTU)
+	}
+	for _, p := range ra.Prefixes {
+		fmt.Printf("Prefix            : %s\n", p)
+	}
+	for _, r := range ra.Routes {
+		fmt.Printf("Route             : %s\n", r)
+	}
+	for _, r := range ra.RDNSS {
+		var list []string
+		for _, ip := range r.Servers {
+			list = append(list, ip.String())
+		}
+		fmt.Printf("DNS servers       : %s (lifetime %s)\n",
+			strings.Join(list, ", "), r.Lifetime)
+	}
+	for _, d := range ra.DNSSL {
+		fmt.Printf("DNS search list   : %s (lifetime %s)\n",
+			strings.Join(d.Domains, ", "), d.Lifetime)
+	}
+
+	// M and O flags point clients to DHCPv6 servers
+	switch {
+	case ra.Managed:
+		fmt.Println("Clients use DHCPv6 for addresses and configuration")
+	case ra.Other:
+		fmt.Println("Clients use DHCPv6 for configuration")
+	}
+	if ra.RouterLifetime == 0 {
+		fmt.Println("Not a default router")
+	}
+
+	fmt.Println()
+
+	stats.msg["ROUTER ADVERT"]++
+
+	x := stats.srv[originIP]
+	x.Advert++
+	x.Name = NameFromIP(originIP)
+	stats.srv[originIP] = x
+
+	updateReport()
+}
+
+// solicitRA sends a router solicitation and returns the routers
+// advertising before the timeout.
+func solicitRA(ctx context.Context, iface string, timeout time.Duration) ([]offer, error) {
+	conn, err := ndp.Listen(iface)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := conn.Solicit(ctx); err != nil {
+		return nil, err
+	}
+	stats.pksent++
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var routers []offer
+	for {
+		ra, remote, err := conn.Receive(ctx)
+		if err != nil {
+			if remote == nil {
+				if err == context.DeadlineExceeded || err == context.Canceled {
+					err = nil
+				}
+				return routers, err
+			}
+			continue
+		}
+
+		stats.pkrec++
+
+		rip := remote.IP.String()
+		rmac := ""
+		if ra.SourceLinkAddr != nil {
+			rmac = ra.SourceLinkAddr.String()
+		}
+		routers = append(routers, offer{rip, rmac})
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+func cmdSentry(ctx context.Context) {
+	var ifaces string
+	var allow string
+	var secs int
+	var interval int
+	var rounds int
+	var routers bool
+
+	flag.StringVar(&ifaces, "i", "", "comma-separated list of network `interfaces` to use")
+	flag.StringVar(&allow, "a", "", "comma-separated list of allowed server IP or MAC `addresses`, or IP/MAC pairs")
+	flag.IntVar(&secs, "t", 5, "timeout in seconds")
+	flag.IntVar(&interval, "n", 60, "interval between discover rounds in seconds")
+	flag.IntVar(&rounds, "m", 3, "warn after `N` rounds without answer from an allowed server")
+	flag.BoolVar(&routers, "r", false, "also check IPv6 router advertisements")
+	flag.Parse()
+
+	if ifaces == "" || allow == "" {
+		usage(os.Args[1])
+		os.Exit(1)
+	}
+
+	servers, err := parseAllowList(allow)
+	checkError(err)
+
+	sentry(ctx, strings.Split(ifaces, ","), servers,
+		time.Duration(secs)*time.Second,
+		time.Duration(interval)*time.Second, rounds, routers)
+}
+
+// allowed is an expected DHCP server, identified by IP address, MAC
+// address or both. Entries for the same server are merged when an answer
+// shows both addresses.
+type allowed struct {
+	ip     string
+	mac    string
+	missed int
+}
+
+func (a *allowed) String() string {
+	switch {
+	case a.ip == "":
+		return a.mac
+	case a.mac == "":
+		return a.ip
+	}
+	return fmt.Sprintf("%s [%s]", a.ip, a.mac)
+}
+
+// parseAllowList parses a comma-separated list of IP or MAC addresses. An
+// IP and a MAC address joined by a slash identify the same server.
+func parseAllowList(s string) ([]*allowed, error) {
+	var list []*allowed
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		a := &allowed{}
+		for _, addr := range strings.Split(entry, "/") {
+			if hw, err := net.ParseMAC(addr); err == nil && a.mac == "" {
+				a.mac = hw.String()
+				continue
+			}
+			if ip := net.ParseIP(addr); ip != nil && a.ip == "" {
+				a.ip = ip.String()
+				continue
+			}
+			return nil, fmt.Errorf("%s: invalid server address", entry)
+		}
+		list = append(list, a)
+	}
+	return list, nil
+}
+
+// matches reports whether an answer comes from the server. Either address
+// is enough, since the MAC address of a server isn't always known.
+func (a *allowed) matches(o offer) bool {
+	return (a.ip != "" && a.ip == o.ip) || (a.mac != "" && a.mac == o.mac)
+}
+
+func isAllowed(servers []*allowed, o offer) bool {
+	for _, a := range servers {
+		if a.matches(o) {
+			return true
+		}
+	}
+	return false
+}
+
+// merge joins the entries matching the IP and MAC addresses of an answer
+// into a single entry for the server.
+func merge(servers []*allowed, o offer) []*allowed {
+	if o.ip == "" || o.mac == "" {
+		return servers
+	}
+
+	var byIP, byMAC *allowed
+	for _, a := range servers {
+		switch {
+		case a.ip == o.ip && a.mac == "":
+			byIP = a
+		case a.mac == o.mac && a.ip == "":
+			byMAC = a
+		}
+	}
+	if byIP == nil || byMAC == nil {
+		return servers
+	}
+
+	byIP.mac = byMAC.mac
+	if byMAC.missed < byIP.missed {
+		byIP.missed = byMAC.missed
+	}
+	list := servers[:0]
+	for _, a := range servers {
+		if a != byMAC {
+			list = append(list, a)
+		}
+	}
+	return list
+}
+
+func alert(format string, a ...interface{}) {
+	fmt.Printf("\n!!! %s ALERT: %s\n", time.Now().Format(time.RFC3339),
+		fmt.Sprintf(format, a...))
+}
+
+func sentry(ctx context.Context, ifaces []string, servers []*allowed, timeout, interval time.Duration, rounds int, routers bool) {
+	for {
+		var offers, adverts []offer
+		failed := false
+		for _, iface := range ifaces {
+			o, err := discover(ctx, iface, timeout, true)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", iface, err)
+				failed = true
+				continue
+			}
+			offers = append(offers, o...)
+
+			if routers {
+				o, err := solicitRA(ctx, iface, timeout)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "%s: %s\n", iface, err)
+					failed = true
+					continue
+				}
+				adverts = append(adverts, o...)
+			}
+		}
+		if ctx.Err() != nil {
+			// interrupted, round is incomplete
+			return
+		}
+
+		// Offers from servers not in the allow list
+		for _, o := range offers {
+			if !isAllowed(servers, o) {
+				alert("offer from unknown server %s [%s] (%s)",
+					o.ip, o.mac, VendorFromMAC(o.mac))
+			}
+		}
+		for _, o := range adverts {
+			if !isAllowed(servers, o) {
+				alert("advertisement from unknown router %s [%s] (%s)",
+					o.ip, o.mac, VendorFromMAC(o.mac))
+			}
+		}
+
+		answers := append(offers, adverts...)
+		for _, o := range answers {
+			servers = merge(servers, o)
+		}
+
+		// Allowed servers that didn't answer
+		for _, a := range servers {
+			answered := false
+			for _, o := range answers {
+				if a.matches(o) {
+					answered = true
+					break
+				}
+			}
+
+			if answered {
+				if a.missed >= rounds {
+					alert("server %s is answering again", a)
+				}
+				a.missed = 0
+				continue
+			}
+			if failed {
+				// round incomplete, the server may be behind the
+				// failed interface
+				continue
+			}
+
+			a.missed++
+			if a.missed == rounds {
+				alert("server %s not answering for %d rounds",
+					a, a.missed)
+			}
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
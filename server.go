@@ -25,7 +25,9 @@ func update(w http.ResponseWriter, r *http.Request) {
 }
 
 func disc(w http.ResponseWriter, r *http.Request) {
-	discover("en0", -1, true)
+	if _, err := discover(r.Context(), "en0", -1, true); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
 }
 
 func status(w http.ResponseWriter, r *http.Request) {
@@ -70,34 +72,83 @@ table td {
         x.open("GET", "/discover/", true);
         x.send(null);
     }
+    // values come from snooped packets, so they are set as text
+    function cell(tr,tag,text) {
+	var c=document.createElement(tag);
+	c.textContent=text;
+	tr.appendChild(c);
+    }
+    function showTable(id,head,rows) {
+	var t=document.createElement("table");
+	var tr=t.createTHead().insertRow();
+	for (var i in head) {
+		cell(tr,"th",head[i]);
+	}
+	var body=t.createTBody();
+	for (var i in rows) {
+		tr=body.insertRow();
+		for (var j in rows[i]) {
+			cell(tr,"td",rows[i][j]);
+		}
+	}
+	document.getElementById(id).replaceChildren(t);
+    }
     function showSrv(id,map) {
-	var h="</th><th>";
-	var s="</td><td>";
-        var t="<table><thead><tr><th>Server IP"+h+"Name"+h+"Offers"+h+"ACKs"+h+"NACKs</th></tr></thead><tbody>";
+	var rows=[];
 	for (var key in map) {
 		var v=map[key]
-        	t+="<tr><td>"+key+s+v.Name+s+v.Offer+s+v.Ack+s+v.Nack+"</td></tr>";
+		var impl=v.Impl ? v.Impl+" ("+Math.round(v.Conf*100)+"%)" : "unknown"
+		rows.push([key,v.Name,impl,v.Offer,v.Ack,v.Nack,v.Advert]);
 	}
-        t+="</tbody></table>";
-        document.getElementById(id).innerHTML=t;
+	showTable(id,["Server IP","Name","Implementation","Offers","ACKs","NACKs","RAs"],rows);
     }
-    function showMap(id,map,head) {
-        var t="<table><thead><tr><th>"+head+"</th><th>Packets</th></tr></thead><tbody>";
+    function showMap(id,map,head,col) {
+	var rows=[];
 	for (var key in map) {
-        	t+="<tr><td>"+key+"</td><td>"+map[key]+"</td></tr>";
+		rows.push([key,map[key]]);
+	}
+	showTable(id,[head,col||"Packets"],rows);
+    }
+    function showLeases(id,list) {
+	var rows=[];
+	for (var i in list) {
+		var v=list[i]
+		rows.push([v.IP,v.MAC,v.State,v.Expiry,v.Server,v.Relay,v.Hostname]);
 	}
-        t+="</tbody></table>";
-        document.getElementById(id).innerHTML=t;
+	showTable(id,["IP address","MAC address","State","Expires","Server","Relay","Host name"],rows);
+    }
+    function showList(id,list) {
+	var ul=document.createElement("ul");
+	for (var i in list) {
+		var li=document.createElement("li");
+		li.textContent=list[i];
+		ul.appendChild(li);
+	}
+	document.getElementById(id).replaceChildren(ul);
     }
     var source = new EventSource("/update/");
     source.addEventListener("message", function(e) {
-	//document.getElementById("result").innerHTML = event.data;
 	stats=JSON.parse(event.data);
-	document.getElementById("packets").innerHTML=stats.Packets;
+	document.getElementById("packets").textContent=stats.Packets;
 	showSrv("servers", stats.Servers)
 	showMap("msgtype", stats.MsgType, "Message type")
 	showMap("vendors", stats.Vendors, "Vendor")
 	showMap("vdclass", stats.VdClass, "Vendor class")
+	if (stats.Devices) {
+		showMap("devices", stats.Devices, "Device class", "Clients")
+	}
+	if (stats.Transact) {
+		showMap("transact", stats.Transact, "Outcome", "Transactions")
+	}
+	if (stats.Failed) {
+		showMap("failed", stats.Failed, "Client MAC", "Outcome")
+	}
+	if (stats.Leases) {
+		showLeases("leases", stats.Leases)
+	}
+	if (stats.Conflicts) {
+		showList("conflicts", stats.Conflicts)
+	}
     }, false);
 		</script>
 
@@ -114,6 +165,16 @@ table td {
 		<div id="vendors">No packets received.</div>
 		<h2>Packets by vendor class</h2>
 		<div id="vdclass">No packets received.</div>
+		<h2>Client devices</h2>
+		<div id="devices">No requests received.</div>
+		<h2>Transactions</h2>
+		<div id="transact">No transactions finished.</div>
+		<h2>Failing clients</h2>
+		<div id="failed">No transactions finished.</div>
+		<h2>Leases</h2>
+		<div id="leases">No leases seen.</div>
+		<h2>Address conflicts</h2>
+		<div id="conflicts">No conflicts found.</div>
 	</body>
 </html>`
 
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"./dhcp"
+)
+
+const markup = `<img src=x onerror=alert(1)>`
+
+func TestLeaseHostnameMarkup(t *testing.T) {
+	leases = leaseTable{
+		ip:       map[string]*Lease{},
+		host:     map[string]string{},
+		assigned: map[string]map[string]bool{},
+	}
+
+	req := dhcp.NewRequestPacket(dhcp.NewDiscoverPacket())
+	req.SetClientMAC("00:11:22:33:44:55")
+	req.SetString(dhcp.HostName, markup)
+	leases.track(req, "0.0.0.0", time.Now())
+
+	ack := dhcp.NewDiscoverPacket()
+	ack.Op = dhcp.BootReply
+	ack.Xid = req.Xid
+	ack.SetClientMAC("00:11:22:33:44:55")
+	ack.SetOption(dhcp.DHCPMessageType, []byte{dhcp.DHCPAck})
+	copy(ack.Yiaddr[:], []byte{10, 0, 0, 50})
+	leases.track(ack, "10.0.0.1", time.Now())
+
+	if len(report.Leases) != 1 || report.Leases[0].Hostname != markup {
+		t.Fatalf("expect lease with host name %q, got %+v", markup, report.Leases)
+	}
+
+	// the status update carries the host name as data, not markup
+	j, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(j), "<img") {
+		t.Fatalf("markup not escaped in status update: %s", j)
+	}
+}
+
+func TestStatusPageText(t *testing.T) {
+	w := httptest.NewRecorder()
+	status(w, httptest.NewRequest("GET", "/", nil))
+
+	// snooped values must be set as text, never parsed as HTML
+	body := w.Body.String()
+	if strings.Contains(body, "innerHTML") {
+		t.Fatal("status page renders values with innerHTML")
+	}
+	if !strings.Contains(body, "textContent") {
+		t.Fatal("status page doesn't set values as text")
+	}
+}
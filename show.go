@@ -3,55 +3,22 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"net"
+	"strings"
 
 	"./dhcp"
 	"./format"
-	"github.com/cmatsuoka/dncomp"
 )
 
-type option struct {
-	Len  int
-	Name string
-}
-
 var (
-	options     map[byte]option
 	messageType map[byte]string
 	op          map[byte]string
+
+	fingerprints = dhcp.DefaultFingerprints
+	requests     = map[uint32]*dhcp.Packet{} // map xid to last request
 )
 
 func init() {
-	options = map[byte]option{
-		dhcp.PadOption:              {0, "Pad Option"},
-		dhcp.Router:                 {-1, "Router"},
-		dhcp.SubnetMask:             {4, "Subnet Mask"},
-		dhcp.DomainNameServer:       {-1, "Domain Name Server"},
-		dhcp.HostName:               {-1, "Host Name"},
-		dhcp.DomainName:             {-1, "Domain Name"},
-		dhcp.BroadcastAddress:       {4, "Broadcast Address"},
-		dhcp.StaticRoute:            {-1, "Static Route"},
-		dhcp.IPAddressLeaseTime:     {4, "IP Address Lease Time"},
-		dhcp.DHCPMessageType:        {1, "DHCP Message Type"},
-		dhcp.ServerIdentifier:       {4, "Server Identifier"},
-		dhcp.InterfaceMTU:           {2, "Server MTU"},
-		dhcp.RenewalTimeValue:       {4, "Renewal Time Value"},
-		dhcp.RebindingTimeValue:     {4, "Rebinding Time Value"},
-		dhcp.VendorSpecific:         {-1, "Vendor Specific"},
-		dhcp.PerformRouterDiscovery: {1, "Perform Router Discovery"},
-		dhcp.NetBIOSNameServer:      {-1, "NetBIOS Name Server"},
-		dhcp.NetBIOSNodeType:        {1, "NetBIOS Node Type"},
-		dhcp.NetBIOSScope:           {-1, "NetBIOS Scope"},
-		dhcp.RequestedIPAddress:     {-1, "Requested IP Address"},
-		dhcp.VendorClassIdentifier:  {-1, "Vendor Class Identifier"},
-		dhcp.MaxDHCPMessageSize:     {2, "Max DHCP Message Size"},
-		dhcp.ParameterRequestList:   {-1, "Parameter Request List"},
-		dhcp.ClientIdentifier:       {-1, "Client Identifier"},
-		dhcp.DomainSearch:           {-1, "Domain Search"},
-		dhcp.UserClass:              {-1, "User Class"},
-		dhcp.ClientFQDN:             {-1, "Client FQDN"},
-		dhcp.WebProxyServer:         {-1, "Web Proxy Server"},
-	}
-
 	messageType = map[byte]string{
 		dhcp.DHCPDiscover: "DHCPDISCOVER",
 		dhcp.DHCPOffer:    "DHCPOFFER",
@@ -77,141 +44,248 @@ func showOptions(p *dhcp.Packet, originIP string) {
 	}
 
 	fmt.Println("Options:")
-loop:
 	for _, o := range opts {
 
 		switch o.Type {
 		case dhcp.EndOption:
 			fmt.Print("End Option")
-			break loop
+			return
 		case dhcp.PadOption:
 			continue
 		}
 
-		if name := options[o.Type].Name; name != "" {
-			fmt.Printf("%24s : ", name)
-		} else {
-			fmt.Printf("%24d : ", o)
-		}
+		fmt.Printf("%24s : ", dhcp.OptionName(o.Type))
 
-		if o.Type == dhcp.VendorClassIdentifier {
+		switch o.Type {
+		case dhcp.VendorClassIdentifier:
 			stats.vdc[format.String(o.Data)]++
-		}
 
-		switch o.Type {
+		case dhcp.RelayAgentInformation:
+			// replies echo the option, count requests only
+			if p.Op != dhcp.BootRequest {
+				break
+			}
+			if r, err := dhcp.ParseRelayAgentInfo(o.Data); err == nil {
+				key := fmt.Sprintf("%s %s", p.Giaddr.String(),
+					format.Printable(r.CircuitID))
+				stats.relay[key]++
+			}
+
 		case dhcp.DHCPMessageType:
-			if m, ok := messageType[o.Data[0]]; ok {
-				fmt.Printf(m)
-				stats.msg[m]++
-
-			} else {
-				s := fmt.Sprintf("<unknown: %d>", o.Data[0])
-				fmt.Printf(s)
-				stats.msg[s]++
+			if len(o.Data) != 1 {
+				break
 			}
 
+			stats.msg[messageName(o.Data[0])]++
+
+			key := serverKey(p, originIP)
 			switch o.Data[0] {
 			case dhcp.DHCPOffer:
-				x := stats.srv[originIP]
+				x := stats.srv[key]
 				x.Offer++
-				x.Name = NameFromIP(originIP)
-				stats.srv[originIP] = x
+				x.Name = NameFromIP(key)
+				stats.srv[key] = x
 			case dhcp.DHCPAck:
-				x := stats.srv[originIP]
+				x := stats.srv[key]
 				x.Ack++
-				x.Name = NameFromIP(originIP)
-				stats.srv[originIP] = x
+				x.Name = NameFromIP(key)
+				stats.srv[key] = x
 			case dhcp.DHCPNack:
-				x := stats.srv[originIP]
+				x := stats.srv[key]
 				x.Nack++
-				x.Name = NameFromIP(originIP)
-				stats.srv[originIP] = x
+				x.Name = NameFromIP(key)
+				stats.srv[key] = x
 			}
+		}
 
-		case dhcp.Router, dhcp.DomainNameServer, dhcp.NetBIOSNameServer:
-			// Multiple IP addresses
-			for n := 0; n < len(o.Data); n += 4 {
-				if n > 0 {
-					fmt.Print(", ")
-				}
-				fmt.Print(format.IPv4String(o.Data[n : n+4]))
-			}
+		if o.Type == dhcp.VendorSpecific {
+			fmt.Println(vendorValue(p, o))
+			continue
+		}
 
-		case dhcp.ServerIdentifier, dhcp.SubnetMask,
-			dhcp.BroadcastAddress, dhcp.RequestedIPAddress:
-			// Single IP address
-			fmt.Print(format.IPv4String(o.Data))
-
-		case dhcp.PerformRouterDiscovery:
-			// yes or no
-			fmt.Print(format.YesNo(o.Data))
-
-		case dhcp.NetBIOSNodeType:
-			// hex byte
-			fmt.Printf("%#02x", o.Data[0])
-
-		case dhcp.MaxDHCPMessageSize, dhcp.InterfaceMTU:
-			// 16-bit integer
-			fmt.Print(format.Uint16B(o.Data))
-
-		case dhcp.IPAddressLeaseTime, dhcp.RenewalTimeValue,
-			dhcp.RebindingTimeValue:
-			// Duration
-			fmt.Printf("%d (%s)", format.Uint32B(o.Data),
-				format.DurationString(o.Data))
-
-		case dhcp.HostName, dhcp.DomainName, dhcp.WebProxyServer,
-			dhcp.NetBIOSScope:
-			// String
-			fmt.Printf(format.String(o.Data))
-
-		case dhcp.DomainSearch:
-			// Compressed domain names (RFC 1035)
-			if s, err := dncomp.Decode(o.Data); err != nil {
-				fmt.Print(s)
-			}
+		fmt.Println(optionValue(o))
+	}
+}
 
-		case dhcp.ClientIdentifier:
-			// Types according to RFC 1700
-			switch o.Data[0] {
-			case 1:
-				fmt.Print(format.MACAddrString(o.Data[1:7]))
-			default:
-				fmt.Printf("type %d (len %d)", o.Data[0], len(o.Data)-1)
-			}
+// vendorValue renders the Vendor Specific Information option, decoded
+// according to the vendor class of the packet.
+func vendorValue(p *dhcp.Packet, o dhcp.Option) string {
+	class, _ := p.Option(dhcp.VendorClassIdentifier)
+	subs, err := dhcp.DecodeVendorSpecific(string(class), o.Data)
+	if err != nil || len(subs) == 0 {
+		// Dump data
+		return format.String(o.Data)
+	}
 
-		case dhcp.VendorSpecific, dhcp.VendorClassIdentifier, dhcp.UserClass:
-			// Dump data
-			fmt.Printf(format.String(o.Data))
-
-		case dhcp.ParameterRequestList:
-			// Parameter list
-			for i, p := range o.Data {
-				if i > 0 {
-					fmt.Printf("\n%24s   ", "")
-				}
-				fmt.Printf("%3d %s", p, options[p].Name)
-			}
+	var list []string
+	for _, s := range subs {
+		list = append(list, fmt.Sprintf("%-20s %s", s.Name+":",
+			subOptionValue(s.Value)))
+	}
+	return strings.Join(list, fmt.Sprintf("\n%24s   ", ""))
+}
+
+func subOptionValue(v interface{}) string {
+	switch x := v.(type) {
+	case []byte:
+		return format.Printable(x)
+	case string:
+		return fmt.Sprintf("%q", x)
+	case []dhcp.PXEMenuItem:
+		var list []string
+		for _, m := range x {
+			list = append(list, m.String())
+		}
+		return strings.Join(list, ", ")
+	}
+	return fmt.Sprint(v)
+}
+
+func messageName(t byte) string {
+	if m, ok := messageType[t]; ok {
+		return m
+	}
+	return fmt.Sprintf("<unknown: %d>", t)
+}
+
+// optionValue renders option data according to the kind of the option
+// in the registry.
+func optionValue(o dhcp.Option) string {
+
+	// Options with special formatting
+	switch o.Type {
+	case dhcp.OptionOverload:
+		// Fields used for options
+		if len(o.Data) != 1 {
+			break
+		}
+		switch o.Data[0] {
+		case dhcp.OverloadFile:
+			return "file"
+		case dhcp.OverloadSname:
+			return "sname"
+		case dhcp.OverloadFile | dhcp.OverloadSname:
+			return "file, sname"
+		}
+		return fmt.Sprintf("<unknown: %d>", o.Data[0])
+
+	case dhcp.NetBIOSNodeType:
+		// hex byte
+		if len(o.Data) == 1 {
+			return fmt.Sprintf("%#02x", o.Data[0])
+		}
+	}
+
+	info, _ := dhcp.LookupOption(o.Type)
+	v, err := info.Kind.Decode(o.Data)
+	if err != nil {
+		return fmt.Sprintf("<invalid: %s>", format.String(o.Data))
+	}
+
+	var list []string
+
+	switch info.Kind {
+	case dhcp.KindIP:
+		return v.(net.IP).String()
+
+	case dhcp.KindIPList:
+		// Multiple IP addresses
+		for _, ip := range v.([]net.IP) {
+			list = append(list, ip.String())
+		}
+		return strings.Join(list, ", ")
+
+	case dhcp.KindIPPairs:
+		// Address and mask or router
+		for _, pair := range v.([][2]net.IP) {
+			list = append(list, pair[0].String()+"/"+pair[1].String())
+		}
+		return strings.Join(list, ", ")
+
+	case dhcp.KindBool:
+		// yes or no
+		return format.YesNo(o.Data)
+
+	case dhcp.KindUint8, dhcp.KindUint16, dhcp.KindUint32, dhcp.KindInt32:
+		return fmt.Sprint(v)
+
+	case dhcp.KindUint16List:
+		for _, x := range v.([]uint16) {
+			list = append(list, fmt.Sprint(x))
+		}
+		return strings.Join(list, ", ")
 
-		case dhcp.ClientFQDN:
-			// Client FQDN format
-			c := []byte{'-', '-', '-', '-'}
-			d := []byte{'N', 'E', 'O', 'S'}
-			for j := range c {
-				if o.Data[0]&(1<<(3-uint(j))) != 0 {
-					c[j] = d[j]
-				}
+	case dhcp.KindDuration:
+		return fmt.Sprintf("%d (%s)", format.Uint32B(o.Data),
+			format.DurationString(o.Data))
+
+	case dhcp.KindString:
+		return format.String(o.Data)
+
+	case dhcp.KindDomainList:
+		// Compressed domain names (RFC 1035)
+		for _, name := range v.([]string) {
+			list = append(list, fmt.Sprintf("%q", name))
+		}
+		return strings.Join(list, ", ")
+
+	case dhcp.KindMessageType:
+		return messageName(o.Data[0])
+
+	case dhcp.KindOptionList:
+		// Parameter list
+		for _, p := range o.Data {
+			list = append(list, fmt.Sprintf("%3d %s", p,
+				dhcp.OptionName(p)))
+		}
+		return strings.Join(list, fmt.Sprintf("\n%24s   ", ""))
+
+	case dhcp.KindClientID:
+		// Ethernet address, or opaque identifier such as a DUID
+		if o.Data[0] == dhcp.HtypeEthernet && len(o.Data) == 7 {
+			return format.RFC1700Types(o.Data)
+		}
+		return fmt.Sprintf("type %d, %s", o.Data[0],
+			format.MACAddrString(o.Data[1:]))
+
+	case dhcp.KindClientFQDN:
+		// Client FQDN format
+		f := v.(dhcp.FQDN)
+		c := []byte{'-', '-', '-', '-'}
+		d := []byte{'N', 'E', 'O', 'S'}
+		for j := range c {
+			if f.Flags&(1<<(3-uint(j))) != 0 {
+				c[j] = d[j]
 			}
-			fmt.Printf("%s %02x %02x ", string(c), o.Data[1],
-				o.Data[2])
-			if o.Data[0]&0x04 == 0 {
-				fmt.Printf("%q", string(o.Data[3:]))
-			} else {
-				fmt.Printf("%q", format.CanonicalWireFormat(o.Data[3:]))
+		}
+		return fmt.Sprintf("%s %02x %02x %q", string(c), f.RCode1,
+			f.RCode2, f.Name)
+
+	case dhcp.KindStaticRoutes, dhcp.KindClasslessRoutes:
+		// Routes
+		for _, r := range v.([]dhcp.Route) {
+			list = append(list, r.String())
+		}
+		return strings.Join(list, fmt.Sprintf("\n%24s   ", ""))
+
+	case dhcp.KindRelayAgentInfo:
+		// Sub-options
+		for _, sub := range v.(*dhcp.RelayAgentInfo).SubOptions() {
+			var s string
+			switch sub.Type {
+			case dhcp.AgentLinkSelection, dhcp.AgentServerIDOverride:
+				s = format.IPv4String(sub.Data)
+			default:
+				s = format.Printable(sub.Data)
 			}
+			list = append(list, fmt.Sprintf("%-18s %s",
+				dhcp.AgentSubOptionName(sub.Type)+":", s))
 		}
-		fmt.Println()
+		return strings.Join(list, fmt.Sprintf("\n%24s   ", ""))
 	}
+
+	// Dump data
+	return format.String(o.Data)
 }
 
 func opcode(o byte) string {
@@ -221,7 +295,96 @@ func opcode(o byte) string {
 	return fmt.Sprintf("<unknown:%d>", o)
 }
 
-func showPacket(p *dhcp.Packet, originIP string) {
+// cString returns the contents of a null-terminated string field.
+func cString(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
+
+// deviceClass returns the device class of the client sending a request,
+// from the fingerprint database.
+func deviceClass(p *dhcp.Packet) string {
+	mac := p.Chaddr.MACAddress().String()
+	if f, ok := fingerprints.Match(p); ok {
+		stats.dev[mac] = f.Class
+		return f.Class
+	}
+
+	// don't forget a client identified in earlier requests
+	if _, ok := stats.dev[mac]; !ok {
+		stats.dev[mac] = "unknown"
+	}
+	if prl := dhcp.RequestFingerprint(p).PRL; prl != "" {
+		return fmt.Sprintf("unknown (PRL %s)", prl)
+	}
+	return "unknown"
+}
+
+// maxRequests is the number of requests kept to compare with replies.
+const maxRequests = 1024
+
+// rememberRequest keeps a request to find the options and flags echoed in
+// the replies.
+func rememberRequest(p *dhcp.Packet) {
+	if len(requests) >= maxRequests {
+		// unanswered requests
+		requests = map[uint32]*dhcp.Packet{}
+	}
+	requests[p.Xid] = p
+}
+
+// serverKey returns the address identifying the server sending a reply.
+// Relayed replies come from the relay agent, so the server identifier is
+// used when present.
+func serverKey(p *dhcp.Packet, originIP string) string {
+	if id := p.ServerID(); id != nil {
+		return id.String()
+	}
+	return originIP
+}
+
+// serverType returns the likely implementation of the server sending a
+// reply, keeping the best guess in the server statistics.
+func serverType(p *dhcp.Packet, originIP string) string {
+	req := requests[p.Xid]
+	if req != nil && req.Chaddr != p.Chaddr {
+		req = nil
+	}
+	switch p.MessageType() {
+	case dhcp.DHCPAck, dhcp.DHCPNack:
+		delete(requests, p.Xid)
+	}
+
+	g := dhcp.GuessServer(p, req)
+	if g.Name == "" {
+		return "unknown"
+	}
+
+	key := serverKey(p, originIP)
+	x := stats.srv[key]
+	if g.Confidence > x.Conf {
+		x.Impl, x.Conf = g.Name, g.Confidence
+		stats.srv[key] = x
+	}
+	return fmt.Sprintf("%s (%.0f%% confidence)", g.Name, g.Confidence*100)
+}
+
+// deviceCount returns the number of clients of each device class.
+func deviceCount() map[string]uint {
+	count := map[string]uint{}
+	for _, class := range stats.dev {
+		count[class]++
+	}
+	return count
+}
+
+// showPacket displays the packet contents and any anomalies found, and
+// returns the anomaly warnings.
+func showPacket(p *dhcp.Packet, originIP string) []string {
 	fmt.Printf("Message opcode    : %s\n", opcode(p.Op))
 	//fmt.Printf("HW address type   : %d\n", p.Htype)
 	//fmt.Printf("HW address length : %d\n", p.Hlen)
@@ -236,13 +399,43 @@ func showPacket(p *dhcp.Packet, originIP string) {
 
 	mac := p.Chaddr.MACAddress().String()
 	fmt.Printf("Client MAC address: %s (%s)\n", mac, VendorFromMAC(mac))
+	if p.Op == dhcp.BootRequest {
+		fmt.Printf("Client device     : %s\n", deviceClass(p))
+		rememberRequest(p)
+	}
+	if p.Op == dhcp.BootReply && originIP != "" {
+		fmt.Printf("Server type       : %s\n", serverType(p, originIP))
+	}
+
+	// sname and file may carry options instead of names
+	var overload byte
+	if b, ok := p.Option(dhcp.OptionOverload); ok && len(b) == 1 {
+		overload = b[0]
+	}
+	if s := cString(p.Sname[:]); s != "" && overload&dhcp.OverloadSname == 0 {
+		fmt.Printf("Server host name  : %q\n", s)
+	}
+	if s := cString(p.File[:]); s != "" && overload&dhcp.OverloadFile == 0 {
+		fmt.Printf("Boot file name    : %q\n", s)
+	}
 
 	showOptions(p, originIP)
 
 	fmt.Println()
 
-	// Update report
+	warnings := checkRoutes(p)
+	warnings = append(warnings, lint(p, originIP)...)
+	for _, w := range warnings {
+		fmt.Println("Warning:", w)
+	}
+
+	updateReport()
 
+	return warnings
+}
+
+// updateReport sends the current statistics to the web status page.
+func updateReport() {
 	report.Packets++
 	report.MsgType = stats.msg
 
@@ -254,11 +447,16 @@ func showPacket(p *dhcp.Packet, originIP string) {
 	report.Vendors = vcount
 	report.VdClass = stats.vdc
 	report.Servers = stats.srv
+	report.Devices = deviceCount()
 
 	j, err := json.Marshal(report)
 	if err != nil {
 		fmt.Errorf("Error: %s\n", err.Error())
 		return
 	}
-	repch <- string(j)
+	select {
+	case repch <- string(j):
+	default:
+		// nobody is listening to status updates
+	}
 }
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"./dhcp6"
+	"./format"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// showMessage6 prints a DHCPv6 message and updates the statistics.
+func showMessage6(m *dhcp6.Message, originIP string) {
+	fmt.Printf("Message type      : %s\n", dhcp6.MessageName(m.Type))
+
+	if m.IsRelay() {
+		fmt.Printf("Hop count         : %d\n", m.HopCount)
+		fmt.Printf("Link address      : %s\n", m.LinkAddress)
+		fmt.Printf("Peer address      : %s\n", m.PeerAddress)
+	} else {
+		fmt.Printf("Transaction ID    : %#06x\n", m.TransactionID)
+	}
+
+	stats.msg[dhcp6.MessageName(m.Type)]++
+
+	switch m.Type {
+	case dhcp6.Advertise:
+		x := stats.srv[originIP]
+		x.Offer++
+		x.Name = NameFromIP(originIP)
+		stats.srv[originIP] = x
+	case dhcp6.Reply:
+		x := stats.srv[originIP]
+		x.Ack++
+		x.Name = NameFromIP(originIP)
+		stats.srv[originIP] = x
+	}
+
+	fmt.Println("Options:")
+	showOptions6(m.Options, 0)
+
+	if m.IsRelay() {
+		if inner, err := m.Inner(); err == nil {
+			fmt.Println("\nRelayed message:")
+			showMessage6(inner, originIP)
+			return
+		}
+	}
+
+	fmt.Println()
+
+	updateReport()
+}
+
+func showOptions6(opts dhcp6.Options, depth int) {
+	indent := strings.Repeat("    ", depth)
+	for _, o := range opts {
+		if o.Code == dhcp6.RelayMessage {
+			continue
+		}
+
+		fmt.Printf("%s%24s : %s\n", indent, dhcp6.OptionName(o.Code),
+			optionValue6(o))
+
+		switch o.Code {
+		case dhcp6.VendorClass:
+			stats.vdc[vendorClass6(o.Data)]++
+
+		case dhcp6.IANAOption, dhcp6.IAPDOption:
+			if ia, err := dhcp6.ParseIANA(o.Data); err == nil {
+				showOptions6(ia.Options, depth+1)
+			}
+
+		case dhcp6.IAAddrOption:
+			if a, err := dhcp6.ParseIAAddress(o.Data); err == nil {
+				showOptions6(a.Options, depth+1)
+			}
+
+		case dhcp6.IAPrefixOption:
+			if p, err := dhcp6.ParseIAPrefix(o.Data); err == nil {
+				showOptions6(p.Options, depth+1)
+			}
+		}
+	}
+}
+
+func optionValue6(o dhcp6.Option) string {
+	var v interface{}
+	var err error
+
+	switch o.Code {
+	case dhcp6.ClientID, dhcp6.ServerID:
+		v = dhcp6.DUID(o.Data)
+
+	case dhcp6.IANAOption, dhcp6.IAPDOption:
+		var ia *dhcp6.IANA
+		if ia, err = dhcp6.ParseIANA(o.Data); err == nil {
+			v = fmt.Sprintf("IAID %s, T1 %s, T2 %s",
+				format.MACAddrString(ia.IAID[:]), ia.T1, ia.T2)
+		}
+
+	case dhcp6.IAAddrOption:
+		v, err = dhcp6.ParseIAAddress(o.Data)
+
+	case dhcp6.IAPrefixOption:
+		v, err = dhcp6.ParseIAPrefix(o.Data)
+
+	case dhcp6.StatusCodeOption:
+		v, err = dhcp6.ParseStatusCode(o.Data)
+
+	case dhcp6.OptionRequest:
+		var codes []uint16
+		if codes, err = dhcp6.ParseOptionRequest(o.Data); err == nil {
+			var list []string
+			for _, c := range codes {
+				list = append(list, dhcp6.OptionName(c))
+			}
+			v = strings.Join(list, ", ")
+		}
+
+	case dhcp6.Preference:
+		if len(o.Data) == 1 {
+			v = o.Data[0]
+		}
+
+	case dhcp6.ElapsedTime:
+		if len(o.Data) == 2 {
+			v = time.Duration(binary.BigEndian.Uint16(o.Data)) * 10 * time.Millisecond
+		}
+
+	case dhcp6.InformationRefreshTime, dhcp6.SolMaxRT:
+		if len(o.Data) == 4 {
+			v = time.Duration(binary.BigEndian.Uint32(o.Data)) * time.Second
+		}
+
+	case dhcp6.ServerUnicast, dhcp6.DNSServers:
+		v, err = dhcp6.ParseIPs(o.Data)
+
+	case dhcp6.DomainList:
+		v, err = dhcp6.ParseDomainList(o.Data)
+
+	case dhcp6.RapidCommit, dhcp6.ReconfigureAccept:
+		return ""
+
+	case dhcp6.VendorClass:
+		if len(o.Data) >= 4 {
+			v = fmt.Sprintf("%s (enterprise %d)", vendorClass6(o.Data),
+				binary.BigEndian.Uint32(o.Data))
+		}
+	}
+
+	if v == nil || err != nil {
+		return format.Printable(o.Data)
+	}
+	return fmt.Sprint(v)
+}
+
+// vendorClass6 renders the vendor class data of the Vendor Class option,
+// after the enterprise number.
+func vendorClass6(b []byte) string {
+	if len(b) < 4 {
+		return format.Printable(b)
+	}
+	var list []string
+	data := b[4:]
+	for len(data) >= 2 {
+		n := int(binary.BigEndian.Uint16(data))
+		if len(data) < 2+n {
+			break
+		}
+		list = append(list, format.String(data[2:2+n]))
+		data = data[2+n:]
+	}
+	return strings.Join(list, " ")
+}
@@ -2,98 +2,298 @@ package main
 
 import (
 	"./dhcp"
+	"./dhcp6"
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"time"
 )
 
-func cmdSnoop() {
+func cmdSnoop(ctx context.Context) {
 	var iface string
+	var file, out string
+	var allow string
+	var table string
+	var fpfile string
+	var fc floodConfig
+	var windowSecs int
+	var v6 bool
 
 	flag.StringVar(&iface, "i", "", "network `interface` to use")
+	flag.StringVar(&file, "r", "", "read packets from pcap or pcapng `file`")
+	flag.StringVar(&out, "w", "", "write packets to pcapng `file`")
+	flag.StringVar(&allow, "a", "", "comma-separated list of allowed server IP or MAC `addresses`")
+	flag.StringVar(&table, "l", "", "write lease table to CSV or JSON `file`")
+	flag.StringVar(&fpfile, "f", "", "load client fingerprints from JSON `file`")
+	flag.BoolVar(&v6, "6", false, "listen to DHCPv6 messages")
+	flag.IntVar(&windowSecs, "window", 10, "flood detection window in seconds")
+	flag.IntVar(&fc.clients, "starve", 50, "alert on discovers from `N` clients in the window (0 to disable)")
+	flag.IntVar(&fc.requests, "storm", 20, "alert on `N` discovers and requests from a client in the window (0 to disable)")
+	flag.IntVar(&fc.naks, "naks", 10, "alert on `N` NAKs in the window (0 to disable)")
+	flag.IntVar(&fc.noOffer, "nooffer", 10, "alert on `N` discovers with no offer in the window (0 to disable)")
 	flag.Parse()
 
-	setupSummary()
+	fc.window = time.Duration(windowSecs) * time.Second
+	floods.configure(fc)
+
+	if fpfile != "" {
+		db, err := dhcp.LoadFingerprintFile(fpfile)
+		checkError(err)
+		// entries in the file take precedence
+		fingerprints = append(db, fingerprints...)
+	}
+
+	if allow != "" {
+		var err error
+		allowList, err = parseAllowList(allow)
+		checkError(err)
+	}
+
+	if v6 {
+		if iface == "" || file != "" || out != "" || table != "" {
+			usage(os.Args[1])
+			os.Exit(1)
+		}
+		go serve(3344)
+		snoop6(ctx, iface)
+		return
+	}
+
+	openCapture(out)
+	defer closeCapture()
+
+	if table != "" {
+		defer func() {
+			checkError(leases.export(table))
+		}()
+	}
+
+	if file != "" {
+		noLookup = true
+		if err := readCapture(ctx, file); err != nil {
+			// still export the leases and show the summary
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		}
+		return
+	}
 
 	go serve(3344)
 
-	snoop(iface)
+	snoop(ctx, iface)
 }
 
+const timeFormat = "2006-01-02 15:04:05.000000"
+
 type message struct {
 	origin string
+	mac    string    // sender hardware address, if known
+	time   time.Time // capture time, if read from a file
 	packet dhcp.Packet
 }
 
-func listen(c chan message, peer dhcp.Peer) {
+// listen sends the packets received by the peer to the channel. If the
+// peer can't read further, snooping is stopped.
+func listen(ctx context.Context, stop context.CancelFunc, c chan message, peer dhcp.Peer) {
 	for {
-		o, remote, err := peer.Receive(-1)
+		o, remote, err := peer.Receive(ctx)
+		if ctx.Err() != nil {
+			return
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			if remote == nil {
+				stop()
+				return
+			}
 			continue
 		}
-		c <- message{remote.IP.String(), o}
+		var mac string
+		if lp, ok := peer.(dhcp.LinkPeer); ok {
+			mac = lp.RemoteMAC().String()
+		}
+		select {
+		case c <- message{remote.IP.String(), mac, time.Time{}, o}:
+		case <-ctx.Done():
+			return
+		}
 	}
 }
 
-func snoop(iface string) {
+func snoop(ctx context.Context, iface string) {
+	defer transactions.flush()
 
-	//var mac string
-	/*if iface != "" {
-		var err error
-		mac, err = MACFromIface(iface)
+	c := make(chan message, 1)
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	if iface != "" {
+		// Capture all DHCP packets in the segment
+		sniffer, err := dhcp.NewRawSniffer(iface)
 		checkError(err)
+		defer sniffer.Close()
+
+		mac, _ := MACFromIface(iface)
 		fmt.Printf("Interface: %s [%s]\n", iface, mac)
-	}*/
 
-	// Set up client
-	client, err := dhcp.NewClient()
-	checkError(err)
-	defer client.Close()
+		go listen(ctx, stop, c, sniffer)
+	} else {
+		// Set up client
+		client, err := dhcp.NewClient()
+		checkError(err)
+		defer client.Close()
 
-	// Set up server
-	server, err := dhcp.NewServer()
-	checkError(err)
-	defer server.Close()
+		// Set up server
+		server, err := dhcp.NewServer()
+		checkError(err)
+		defer server.Close()
 
-	c := make(chan message, 1)
-	go listen(c, client)
-	go listen(c, server)
+		go listen(ctx, stop, c, client)
+		go listen(ctx, stop, c, server)
+	}
+
+	// finish idle transactions even if no packets arrive
+	ticker := time.NewTicker(expireInterval)
+	defer ticker.Stop()
 
 	for {
-		msg := <-c
-		stats.pkrec++
-		p := msg.packet
-
-		rip := msg.origin
-		pmac := p.Chaddr.MACAddress().String()
-
-		var rmac string
-		switch rip {
-		case "0.0.0.0":
-			rmac = pmac
-		/*case myip:	// FIXME: check against local ifaces
-		rmac = mac*/
-		default:
-			rmac = MACFromIP(rip)
+		select {
+		case msg := <-c:
+			process(msg)
+		case now := <-ticker.C:
+			for _, s := range transactions.expire(now) {
+				fmt.Println(s)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// readCapture processes the DHCP packets in a capture file, until the end
+// of the file or an error that prevents reading further.
+func readCapture(ctx context.Context, name string) error {
+	cp, err := dhcp.OpenCapture(name)
+	if err != nil {
+		return err
+	}
+	defer cp.Close()
+
+	fmt.Printf("File: %s\n", name)
+	defer transactions.flush()
+
+	for {
+		o, remote, err := cp.Receive(ctx)
+		if err == io.EOF || ctx.Err() != nil {
+			return nil
+		}
+		if remote == nil {
+			// can't read further
+			return err
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			continue
 		}
+		mac := cp.RemoteMAC().String()
+		process(message{remote.IP.String(), mac, cp.Timestamp(), o})
+	}
+}
+
+func process(msg message) {
+	stats.pkrec++
+	p := msg.packet
+
+	rip := msg.origin
+	pmac := p.Chaddr.MACAddress().String()
+
+	var rmac string
+	switch {
+	case msg.mac != "":
+		rmac = msg.mac
+	case rip == "0.0.0.0":
+		rmac = pmac
+	default:
+		rmac = MACFromIP(rip)
+	}
+
+	stats.pkproc++
+	stats.count[rmac]++
+
+	if rip == "0.0.0.0" {
+		fmt.Printf("\n<<< Broadcast packet\n")
+	} else {
+		fmt.Printf("\n<<< Packet from %s (%s)\n",
+			rip, NameFromIP(rip))
+		fmt.Printf("    MAC address: %s (%s)\n",
+			rmac, VendorFromMAC(rmac))
+	}
+	t := msg.time
+	if t.IsZero() {
+		t = time.Now()
+	} else {
+		fmt.Printf("    Time: %s\n", t.Format(timeFormat))
+	}
+
+	floods.track(&p, t)
+	done := transactions.track(&p, rip, t)
+	conflicts := leases.track(&p, rip, t)
+	warnings := showPacket(&p, rip)
+	for _, w := range conflicts {
+		fmt.Println("Warning:", w)
+	}
+	warnings = append(warnings, conflicts...)
+	for _, s := range done {
+		fmt.Println(s)
+	}
+	record(t, &p, rip, rmac, "", "", verdict(&p, rip, rmac, warnings))
+}
+
+// snoop6 shows DHCPv6 messages exchanged between clients, servers and
+// relay agents.
+func snoop6(ctx context.Context, iface string) {
+	sniffer, err := dhcp6.NewSniffer(iface)
+	checkError(err)
+	defer sniffer.Close()
+
+	mac, _ := MACFromIface(iface)
+	fmt.Printf("Interface: %s [%s]\n", iface, mac)
 
-		/*if iface != "" && mac != pmac {
+	for {
+		m, remote, err := sniffer.Receive(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			if remote == nil {
+				// can't read further
+				return
+			}
 			continue
-		}*/
+		}
 
+		stats.pkrec++
 		stats.pkproc++
-		stats.count[rmac]++
 
-		if rip == "0.0.0.0" {
-			fmt.Printf("\n<<< Broadcast packet\n")
-		} else {
-			fmt.Printf("\n<<< Packet from %s (%s)\n",
-				rip, NameFromIP(rip))
+		rip := remote.IP.String()
+		rmac := senderMAC6(m)
+		if rmac == "" {
+			if hw := sniffer.RemoteMAC(); hw != nil {
+				rmac = hw.String()
+			}
+		}
+		if rmac != "" {
+			stats.count[rmac]++
+		}
+
+		fmt.Printf("\n<<< Packet from %s (%s)\n", rip, NameFromIP(rip))
+		if rmac != "" {
 			fmt.Printf("    MAC address: %s (%s)\n",
 				rmac, VendorFromMAC(rmac))
 		}
 
-		showPacket(&p, rip)
+		showMessage6(m, rip)
 	}
 }
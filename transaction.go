@@ -0,0 +1,247 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"sort"
+	"time"
+
+	"./dhcp"
+)
+
+// Transactions without packets for this long are considered finished.
+// Clients retransmit with exponential backoff up to 64 seconds (RFC 2131
+// section 4.1), so retransmissions stay in the same transaction.
+const transactionTimeout = 90 * time.Second
+
+// How often idle transactions are checked when snooping live.
+const expireInterval = time.Second
+
+// Transaction outcomes
+const (
+	outcomeComplete    = "completed"
+	outcomeNak         = "answered with NAK"
+	outcomeNoOffer     = "discover with no offer"
+	outcomeNoRequest   = "offer never requested"
+	outcomeNoReply     = "request with no reply"
+	outcomeUnsolicited = "offer with no discover"
+)
+
+// transactionKey identifies a client conversation.
+type transactionKey struct {
+	xid uint32
+	mac string
+}
+
+// transaction is a DORA exchange between a client and the servers. A
+// renewal is a transaction without discover and offer.
+type transaction struct {
+	transactionKey
+	server   string
+	discover time.Time
+	offer    time.Time
+	request  time.Time
+	reply    time.Time
+	nak      bool
+	last     time.Time
+	elem     *list.Element // position in the idle list
+}
+
+// outcome returns how a finished transaction ended.
+func (t *transaction) outcome() string {
+	switch {
+	case t.nak:
+		return outcomeNak
+	case !t.reply.IsZero():
+		return outcomeComplete
+	case !t.request.IsZero():
+		return outcomeNoReply
+	case t.offer.IsZero():
+		return outcomeNoOffer
+	case t.discover.IsZero():
+		return outcomeUnsolicited
+	}
+	return outcomeNoRequest
+}
+
+// latency accumulates the latency of a transaction step.
+type latency struct {
+	count uint
+	total time.Duration
+	max   time.Duration
+}
+
+func (l *latency) add(from, to time.Time) {
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		return
+	}
+	d := to.Sub(from)
+	l.count++
+	l.total += d
+	if d > l.max {
+		l.max = d
+	}
+}
+
+func (l *latency) String() string {
+	if l.count == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("avg %s, max %s (%d)", l.total/time.Duration(l.count),
+		l.max, l.count)
+}
+
+// transactionTracker correlates packets by transaction ID and client MAC
+// address.
+type transactionTracker struct {
+	open     map[transactionKey]*transaction
+	idle     *list.List        // open transactions, least recently seen first
+	now      time.Time         // time of the last packet or check
+	outcome  map[string]uint   // map outcome to count
+	failed   map[string]string // map client MAC to last failed outcome
+	offer    latency           // discover to offer
+	request  latency           // offer to request
+	reply    latency           // request to ACK or NAK
+	complete latency           // discover to ACK
+}
+
+var transactions = transactionTracker{
+	open:    map[transactionKey]*transaction{},
+	idle:    list.New(),
+	outcome: map[string]uint{},
+	failed:  map[string]string{},
+}
+
+// track adds a packet seen at the given time to its transaction, and
+// returns the descriptions of the transactions it finished.
+func (tt *transactionTracker) track(p *dhcp.Packet, originIP string, now time.Time) []string {
+	done := tt.expire(now)
+
+	key := transactionKey{p.Xid, p.Chaddr.MACAddress().String()}
+	t := tt.open[key]
+	if t == nil {
+		t = &transaction{transactionKey: key}
+		t.elem = tt.idle.PushBack(t)
+		tt.open[key] = t
+	} else {
+		tt.idle.MoveToBack(t.elem)
+	}
+	t.last = now
+
+	switch p.MessageType() {
+	case dhcp.DHCPDiscover:
+		if t.discover.IsZero() {
+			t.discover = now
+		}
+	case dhcp.DHCPOffer:
+		if t.offer.IsZero() {
+			t.offer = now
+			t.server = originIP
+		}
+	case dhcp.DHCPRequest:
+		if t.request.IsZero() {
+			t.request = now
+		}
+	case dhcp.DHCPAck, dhcp.DHCPNack:
+		t.reply = now
+		t.nak = p.MessageType() == dhcp.DHCPNack
+		t.server = originIP
+		done = append(done, tt.finish(t, now))
+	default:
+		// not part of a DORA exchange
+		if t.discover.IsZero() && t.offer.IsZero() && t.request.IsZero() {
+			tt.idle.Remove(t.elem)
+			delete(tt.open, key)
+		}
+	}
+
+	return done
+}
+
+// expire finishes the transactions idle since before the timeout.
+func (tt *transactionTracker) expire(now time.Time) []string {
+	tt.now = now
+	var done []string
+	for e := tt.idle.Front(); e != nil; e = tt.idle.Front() {
+		t := e.Value.(*transaction)
+		if now.Sub(t.last) <= transactionTimeout {
+			break
+		}
+		done = append(done, tt.finish(t, now))
+	}
+	return done
+}
+
+// flush finishes and shows all open transactions.
+func (tt *transactionTracker) flush() {
+	for e := tt.idle.Front(); e != nil; e = tt.idle.Front() {
+		fmt.Println(tt.finish(e.Value.(*transaction), tt.now))
+	}
+}
+
+// finish ends a transaction found finished at the given time.
+func (tt *transactionTracker) finish(t *transaction, now time.Time) string {
+	tt.idle.Remove(t.elem)
+	delete(tt.open, t.transactionKey)
+
+	o := t.outcome()
+	tt.outcome[o]++
+
+	tt.offer.add(t.discover, t.offer)
+	tt.request.add(t.offer, t.request)
+	tt.reply.add(t.request, t.reply)
+
+	if o == outcomeComplete {
+		delete(tt.failed, t.mac)
+		if !t.discover.IsZero() {
+			tt.complete.add(t.discover, t.reply)
+		}
+	} else {
+		tt.failed[t.mac] = o
+	}
+	if o == outcomeNoOffer {
+		floods.unanswered(now)
+	}
+
+	report.Transact = tt.outcome
+	report.Failed = tt.failed
+
+	s := fmt.Sprintf("Transaction %#08x from %s: %s", t.xid, t.mac, o)
+	if t.server != "" {
+		s += fmt.Sprintf(" (server %s)", t.server)
+	}
+	return s
+}
+
+// summary shows the transaction outcomes, step latencies and clients
+// failing to get addresses.
+func (tt *transactionTracker) summary() {
+	if len(tt.outcome) == 0 {
+		return
+	}
+
+	fmt.Println("\nTransactions")
+	for key, val := range tt.outcome {
+		fmt.Printf("  %-22.22s : %d\n", key, val)
+	}
+
+	fmt.Println("\nTransaction latency")
+	fmt.Println("  Discover to offer :", &tt.offer)
+	fmt.Println("  Offer to request  :", &tt.request)
+	fmt.Println("  Request to reply  :", &tt.reply)
+	fmt.Println("  Complete exchange :", &tt.complete)
+
+	if len(tt.failed) > 0 {
+		var macs []string
+		for mac := range tt.failed {
+			macs = append(macs, mac)
+		}
+		sort.Strings(macs)
+
+		fmt.Println("\nFailing clients")
+		for _, mac := range macs {
+			fmt.Printf("  %s (%-8.8s) : %s\n", mac, VendorFromMAC(mac),
+				tt.failed[mac])
+		}
+	}
+}
@@ -28,7 +28,14 @@ func MACFromIface(s string) (string, error) {
 	return "", fmt.Errorf("%s: no such interface", s)
 }
 
+// Disable reverse DNS and ARP lookups, as when reading capture files from
+// other networks
+var noLookup bool
+
 func NameFromIP(addr string) string {
+	if noLookup {
+		return ""
+	}
 	names, err := net.LookupAddr(addr)
 	if err != nil {
 		return ""
@@ -37,6 +44,9 @@ func NameFromIP(addr string) string {
 }
 
 func MACFromIP(addr string) string {
+	if noLookup {
+		return ""
+	}
 	arp.CacheUpdate()
 	mac := arp.Search(addr)
 	if mac != "" {